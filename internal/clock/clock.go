@@ -0,0 +1,22 @@
+package clock
+
+import "time"
+
+// Clock tells the current time, it is injected wherever behavior depends on it so that it can be tested
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the clock of the operating system
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Func turns a function into a Clock, typically to freeze or move time in tests
+type Func func() time.Time
+
+func (f Func) Now() time.Time {
+	return f()
+}
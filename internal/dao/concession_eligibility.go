@@ -0,0 +1,25 @@
+package dao
+
+import "github.com/benoitmasson/theater-reservation-kata/internal/types"
+
+type ConcessionEligibilityDAO struct{}
+
+func NewConcessionEligibilityDAO() *ConcessionEligibilityDAO {
+	return &ConcessionEligibilityDAO{}
+}
+
+// FetchEligibility simulates fetching the proofs of eligibility customers have given
+func (dao *ConcessionEligibilityDAO) FetchEligibility(customerID int64, ticketType types.TicketType) (types.Eligibility, error) {
+	if !ticketType.IsConcession() {
+		return types.EligibilityNotRequired, nil
+	}
+	switch {
+	case customerID == 3 && ticketType == types.TicketTypeStudent:
+		return types.EligibilityVerified, nil
+	case customerID == 6 && ticketType == types.TicketTypeStudent:
+		// student card expired
+		return types.EligibilityIneligible, nil
+	default:
+		return types.EligibilityCheckAtDoor, nil
+	}
+}
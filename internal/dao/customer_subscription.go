@@ -1,12 +1,23 @@
 package dao
 
+import "github.com/benoitmasson/theater-reservation-kata/internal/types"
+
 type CustomerSubscriptionDAO struct{}
 
+func NewCustomerSubscriptionDAO() *CustomerSubscriptionDAO {
+	return &CustomerSubscriptionDAO{}
+}
+
 // FetchCustomerSubscription simulates fetching data from Customer advantages
-func (dao *CustomerSubscriptionDAO) FetchCustomerSubscription(customerID int64) bool {
-	isSubscribed := false
-	if customerID == 1 {
-		isSubscribed = true
+func (dao *CustomerSubscriptionDAO) FetchCustomerSubscription(customerID int64) (types.SubscriptionTier, error) {
+	switch customerID {
+	case 1:
+		return types.SubscriptionTierGold, nil
+	case 4:
+		return types.SubscriptionTierBasic, nil
+	case 5:
+		return types.SubscriptionTierPatron, nil
+	default:
+		return types.SubscriptionTierNone, nil
 	}
-	return isSubscribed
 }
@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+var ErrInsufficientPoints = errors.New("insufficient loyalty points")
+
+type LoyaltyLedgerDAO struct {
+	entries map[int64][]types.LoyaltyEntry
+	mutex   *sync.RWMutex
+}
+
+func NewLoyaltyLedgerDAO() *LoyaltyLedgerDAO {
+	return &LoyaltyLedgerDAO{
+		entries: make(map[int64][]types.LoyaltyEntry),
+		mutex:   &sync.RWMutex{},
+	}
+}
+
+func (dao *LoyaltyLedgerDAO) AppendLoyaltyEntry(entry types.LoyaltyEntry) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	entries := dao.entries[entry.CustomerID]
+	if entry.Kind == types.LoyaltyEntryRedeem && types.LoyaltyBalance(entries)+entry.Points < 0 {
+		return ErrInsufficientPoints
+	}
+	dao.entries[entry.CustomerID] = append(entries, entry)
+	return nil
+}
+
+func (dao *LoyaltyLedgerDAO) snapshot() func() {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	entries := make(map[int64][]types.LoyaltyEntry, len(dao.entries))
+	for customerID, customerEntries := range dao.entries {
+		entries[customerID] = slices.Clone(customerEntries)
+	}
+	return func() {
+		dao.mutex.Lock()
+		defer dao.mutex.Unlock()
+
+		dao.entries = entries
+	}
+}
+
+func (dao *LoyaltyLedgerDAO) FetchLoyaltyEntries(customerID int64) ([]types.LoyaltyEntry, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	return slices.Clone(dao.entries[customerID]), nil
+}
@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestLoyaltyLedger(t *testing.T) {
+	ledger := NewLoyaltyLedgerDAO()
+	err := ledger.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 1, ReservationID: 1, Kind: types.LoyaltyEntryEarn, Points: 50})
+	if err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+
+	// the balance of a customer never goes below 0 with redemptions
+	err = ledger.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 1, ReservationID: 2, Kind: types.LoyaltyEntryRedeem, Points: -60})
+	if !errors.Is(err, ErrInsufficientPoints) {
+		t.Errorf("Expected ErrInsufficientPoints, got %v", err)
+	}
+	err = ledger.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 2, ReservationID: 3, Kind: types.LoyaltyEntryRedeem, Points: -10})
+	if !errors.Is(err, ErrInsufficientPoints) {
+		t.Errorf("Expected ErrInsufficientPoints for another customer, got %v", err)
+	}
+	err = ledger.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 1, ReservationID: 2, Kind: types.LoyaltyEntryRedeem, Points: -50})
+	if err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+
+	entries, err := ledger.FetchLoyaltyEntries(1)
+	if err != nil {
+		t.Fatalf("Failed to fetch entries: %v", err)
+	}
+	if len(entries) != 2 || types.LoyaltyBalance(entries) != 0 {
+		t.Errorf("Expected 2 entries with a balance of 0, got %+v", entries)
+	}
+}
@@ -0,0 +1,20 @@
+CREATE TABLE seats (
+	performance_id INTEGER NOT NULL,
+	seat_id        TEXT    NOT NULL,
+	zone_index     INTEGER NOT NULL,
+	zone_category  TEXT    NOT NULL,
+	row_index      INTEGER NOT NULL,
+	seat_index     INTEGER NOT NULL,
+	status         TEXT    NOT NULL,
+	PRIMARY KEY (performance_id, seat_id)
+);
+
+CREATE TABLE reservations (
+	reservation_id INTEGER PRIMARY KEY,
+	performance_id INTEGER NOT NULL,
+	status         TEXT    NOT NULL,
+	-- full reservation, as JSON; the columns above duplicate the fields used in queries
+	document       TEXT    NOT NULL
+);
+
+CREATE INDEX reservations_status ON reservations (status);
@@ -0,0 +1,3 @@
+ALTER TABLE reservations ADD COLUMN voucher_code TEXT;
+
+CREATE INDEX reservations_voucher_code ON reservations (voucher_code);
@@ -0,0 +1 @@
+CREATE INDEX reservations_performance_id ON reservations (performance_id);
@@ -0,0 +1,11 @@
+CREATE TABLE loyalty_entries (
+	entry_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	customer_id    INTEGER NOT NULL,
+	reservation_id INTEGER NOT NULL,
+	kind           TEXT    NOT NULL,
+	points         INTEGER NOT NULL,
+	-- RFC 3339 timestamp, with nanoseconds
+	at             TEXT    NOT NULL
+);
+
+CREATE INDEX loyalty_entries_customer_id ON loyalty_entries (customer_id);
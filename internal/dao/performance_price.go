@@ -1,18 +1,55 @@
 package dao
 
-import "math/big"
+import (
+	"math/big"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
 
 type PerformancePriceDAO struct{}
 
-func NewPerformancePriceDAO() PerformancePriceDAO {
-	return PerformancePriceDAO{}
+func NewPerformancePriceDAO() *PerformancePriceDAO {
+	return &PerformancePriceDAO{}
+}
+
+// FetchPerformancePrice simulates a performance pricing repository,
+// prices are in the currency of the country where the performance takes place
+func (dao *PerformancePriceDAO) FetchPerformancePrice(performanceID int64) (types.PerformancePrice, error) {
+	price := types.PerformancePrice{
+		CategoryRatios: map[types.ZoneCategory]*big.Rat{
+			types.ZoneCategoryPremium: big.NewRat(3, 2),
+		},
+		TicketTypeRatios: map[types.TicketType]map[types.ZoneCategory]*big.Rat{
+			types.TicketTypeChild:   allCategories(big.NewRat(1, 2)),
+			types.TicketTypeSenior:  allCategories(big.NewRat(4, 5)),
+			types.TicketTypeStudent: {types.ZoneCategoryStandard: big.NewRat(3, 5), types.ZoneCategoryPremium: big.NewRat(4, 5)},
+			// unemployed visitors are only offered standard seats
+			types.TicketTypeUnemployed: {types.ZoneCategoryStandard: big.NewRat(1, 2)},
+			types.TicketTypeDisabled:   allCategories(big.NewRat(1, 2)),
+		},
+		Groups: []types.GroupPricing{
+			{Name: "GROUP", MinSeats: 6, Discount: big.NewRat(10, 100)},
+			{Name: "FAMILY", Tickets: map[types.TicketType]int{types.TicketTypeAdult: 2, types.TicketTypeChild: 2}, Discount: big.NewRat(15, 100)},
+		},
+	}
+	switch performanceID {
+	case 1:
+		price.SeatPrice = money.MustParse("35.00", money.EUR)
+	case 4:
+		// touring production in London
+		price.SeatPrice = money.MustParse("32.00", money.GBP)
+	default:
+		price.SeatPrice = money.MustParse("28.50", money.EUR)
+	}
+	return price, nil
 }
 
-// FetchPerformancePrice simulates a performance pricing repository
-func (dao *PerformancePriceDAO) FetchPerformancePrice(performanceID int64) *big.Float {
-	if performanceID == 1 {
-		return big.NewFloat(35.00)
-	} else {
-		return big.NewFloat(28.50)
+// allCategories returns the same ratio for all zone categories
+func allCategories(ratio *big.Rat) map[types.ZoneCategory]*big.Rat {
+	ratios := make(map[types.ZoneCategory]*big.Rat, len(types.ZoneCategories))
+	for _, category := range types.ZoneCategories {
+		ratios[category] = ratio
 	}
+	return ratios
 }
@@ -0,0 +1,438 @@
+package dao
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+var (
+	ErrPriceNotFound   = errors.New("no price for performance")
+	ErrMissingCategory = errors.New("missing zone category price")
+)
+
+// PriceListDAO provides performance prices from a price list file, which can be reloaded while running.
+//
+// The file is a JSON document, e.g.
+//
+//	{
+//	  "default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}},
+//	              "ticketTypes": {"CHILD": "0.5", "STUDENT": {"STANDARD": {"price": "17.00"}, "PREMIUM": {"multiplier": "0.8"}}},
+//	              "groups": [{"name": "GROUP", "minSeats": 6, "discount": "0.1"}, {"name": "FAMILY", "tickets": {"ADULT": 2, "CHILD": 2}, "discount": "0.15"}]},
+//	  "performances": {
+//	    "1": {"currency": "EUR", "seatPrice": "35.00", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"price": "52.50"}},
+//	          "demand": {"scope": "ROOM", "tiers": [{"name": "LOW", "minFillRatio": "0", "multiplier": "0.9"}, {"name": "HIGH", "minFillRatio": "0.8", "multiplier": "1.2"}]}}
+//	  }
+//	}
+//
+// Every zone category must be priced, either with an absolute price or with a multiplier of the seat price.
+// Performances without their own entry use the default one, if any.
+// Demand pricing is optional, seats are priced at the multiplier of the tier reached by the fill ratio
+// of the room or of the zone, and at the plain price below the first tier.
+// Ticket types other than adult are only sold when they are priced: either with a multiplier for all the zone categories,
+// or with an absolute price or a multiplier of the category price for each category they are sold in.
+// Groups are optional, the discount of the best one the reservation matches is granted.
+type PriceListDAO struct {
+	path      string
+	priceList priceList
+	// modTime and size tell the version of the file read last, valid or not
+	modTime time.Time
+	size    int64
+	mutex   *sync.RWMutex
+}
+
+type priceList struct {
+	defaultPrice *types.PerformancePrice
+	performances map[int64]types.PerformancePrice
+}
+
+type priceListFile struct {
+	Default      *priceListEntry          `json:"default"`
+	Performances map[int64]priceListEntry `json:"performances"`
+}
+
+type priceListEntry struct {
+	Currency    money.Currency                                `json:"currency"`
+	SeatPrice   string                                        `json:"seatPrice"`
+	Categories  map[types.ZoneCategory]priceListCategoryEntry `json:"categories"`
+	Demand      *priceListDemandEntry                         `json:"demand"`
+	TicketTypes map[types.TicketType]priceListTicketTypeEntry `json:"ticketTypes"`
+	Groups      []priceListGroupEntry                         `json:"groups"`
+}
+
+// priceListCategoryEntry prices a zone category, with exactly one of its fields
+type priceListCategoryEntry struct {
+	Price      string `json:"price,omitempty"`
+	Multiplier string `json:"multiplier,omitempty"`
+}
+
+type priceListDemandEntry struct {
+	Scope types.DemandScope `json:"scope"`
+	Tiers []struct {
+		Name         string `json:"name"`
+		MinFillRatio string `json:"minFillRatio"`
+		Multiplier   string `json:"multiplier"`
+	} `json:"tiers"`
+}
+
+// priceListTicketTypeEntry prices a ticket type, either with a multiplier for all categories, or per category
+type priceListTicketTypeEntry struct {
+	Multiplier string
+	Categories map[types.ZoneCategory]priceListCategoryEntry
+}
+
+func (e *priceListTicketTypeEntry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &e.Multiplier)
+	}
+	return json.Unmarshal(data, &e.Categories)
+}
+
+type priceListGroupEntry struct {
+	Name     string                   `json:"name"`
+	MinSeats int                      `json:"minSeats"`
+	Tickets  map[types.TicketType]int `json:"tickets"`
+	Discount string                   `json:"discount"`
+}
+
+// LoadPriceList reads the price list file at path
+func LoadPriceList(path string) (*PriceListDAO, error) {
+	dao := &PriceListDAO{
+		path:  path,
+		mutex: &sync.RWMutex{},
+	}
+	err := dao.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return dao, nil
+}
+
+// FetchPerformancePrice returns the prices of the performance from the last valid price list read
+func (dao *PriceListDAO) FetchPerformancePrice(performanceID int64) (types.PerformancePrice, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	price, ok := dao.priceList.performances[performanceID]
+	if ok {
+		return price, nil
+	}
+	if dao.priceList.defaultPrice != nil {
+		return *dao.priceList.defaultPrice, nil
+	}
+	return types.PerformancePrice{}, fmt.Errorf("%w #%d", ErrPriceNotFound, performanceID)
+}
+
+// Reload reads the price list file again. When it is not valid, an error is returned
+// and the prices read previously remain in effect.
+func (dao *PriceListDAO) Reload() error {
+	f, err := os.Open(dao.path)
+	if err != nil {
+		return fmt.Errorf("open price list: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat price list: %w", err)
+	}
+	list, err := parsePriceList(f)
+
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	dao.modTime = info.ModTime()
+	dao.size = info.Size()
+	if err != nil {
+		return fmt.Errorf("%s: %w", dao.path, err)
+	}
+	dao.priceList = list
+	return nil
+}
+
+// Watch reloads the price list every interval when the file has been modified, until ctx is done.
+// Failures are reported to onError, when not nil, once per version of the file, and the previous prices remain in effect.
+func (dao *PriceListDAO) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	statFailed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(dao.path)
+			if err != nil {
+				// a missing file is reported once, until it is back
+				if !statFailed && onError != nil {
+					onError(err)
+				}
+				statFailed = true
+				continue
+			}
+			statFailed = false
+			dao.mutex.RLock()
+			modified := !info.ModTime().Equal(dao.modTime) || info.Size() != dao.size
+			dao.mutex.RUnlock()
+			if !modified {
+				continue
+			}
+			err = dao.Reload()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// parsePriceList reads and validates a price list, all the validation errors are reported together
+func parsePriceList(r io.Reader) (priceList, error) {
+	var file priceListFile
+	err := json.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return priceList{}, fmt.Errorf("decode price list: %w", err)
+	}
+
+	list := priceList{
+		performances: make(map[int64]types.PerformancePrice, len(file.Performances)),
+	}
+	var errs []error
+	if file.Default != nil {
+		price, err := file.Default.performancePrice()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("default: %w", err))
+		}
+		list.defaultPrice = &price
+	}
+
+	performanceIDs := make([]int64, 0, len(file.Performances))
+	for performanceID := range file.Performances {
+		performanceIDs = append(performanceIDs, performanceID)
+	}
+	slices.Sort(performanceIDs)
+	for _, performanceID := range performanceIDs {
+		entry := file.Performances[performanceID]
+		price, err := entry.performancePrice()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("performance #%d: %w", performanceID, err))
+		}
+		list.performances[performanceID] = price
+	}
+
+	if len(errs) > 0 {
+		return priceList{}, errors.Join(errs...)
+	}
+	return list, nil
+}
+
+// performancePrice validates the entry, and converts absolute category prices into ratios of the seat price
+func (e priceListEntry) performancePrice() (types.PerformancePrice, error) {
+	if e.Currency == "" {
+		return types.PerformancePrice{}, errors.New("missing currency")
+	}
+	seatPrice, err := money.Parse(e.SeatPrice, e.Currency)
+	if err != nil {
+		return types.PerformancePrice{}, fmt.Errorf("seat price: %w", err)
+	}
+	if seatPrice.Sign() <= 0 {
+		return types.PerformancePrice{}, fmt.Errorf("seat price must be positive, got %s", e.SeatPrice)
+	}
+
+	price := types.PerformancePrice{
+		SeatPrice:      seatPrice,
+		CategoryRatios: make(map[types.ZoneCategory]*big.Rat, len(e.Categories)),
+	}
+	var errs []error
+	for _, category := range types.ZoneCategories {
+		categoryEntry, ok := e.Categories[category]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrMissingCategory, category))
+			continue
+		}
+		ratio, err := categoryEntry.ratio(seatPrice.Currency, seatPrice.Rat())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", category, err))
+			continue
+		}
+		price.CategoryRatios[category] = ratio
+	}
+	for category := range e.Categories {
+		if !slices.Contains(types.ZoneCategories, category) {
+			errs = append(errs, fmt.Errorf("unknown zone category %s", category))
+		}
+	}
+	if e.Demand != nil {
+		demand, err := e.Demand.demandPricing()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("demand: %w", err))
+		}
+		price.Demand = &demand
+	}
+	if len(e.TicketTypes) > 0 {
+		price.TicketTypeRatios = make(map[types.TicketType]map[types.ZoneCategory]*big.Rat, len(e.TicketTypes))
+	}
+	for ticketType, ticketTypeEntry := range e.TicketTypes {
+		if !slices.Contains(types.TicketTypes, ticketType) {
+			errs = append(errs, fmt.Errorf("unknown ticket type %s", ticketType))
+			continue
+		}
+		ratios, err := ticketTypeEntry.ratios(price)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ticket type %s: %w", ticketType, err))
+			continue
+		}
+		price.TicketTypeRatios[ticketType] = ratios
+	}
+	for i, groupEntry := range e.Groups {
+		group, err := groupEntry.groupPricing()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("group #%d: %w", i+1, err))
+			continue
+		}
+		price.Groups = append(price.Groups, group)
+	}
+	return price, errors.Join(errs...)
+}
+
+// ratios returns the ratio of the ticket type price to the category price, for each category the ticket type is sold in
+func (e priceListTicketTypeEntry) ratios(price types.PerformancePrice) (map[types.ZoneCategory]*big.Rat, error) {
+	ratios := make(map[types.ZoneCategory]*big.Rat, len(types.ZoneCategories))
+	if e.Multiplier != "" {
+		multiplier, ok := new(big.Rat).SetString(e.Multiplier)
+		if !ok || multiplier.Sign() < 0 {
+			return nil, fmt.Errorf("invalid multiplier %q", e.Multiplier)
+		}
+		for _, category := range types.ZoneCategories {
+			ratios[category] = multiplier
+		}
+		return ratios, nil
+	}
+
+	var errs []error
+	for category, categoryEntry := range e.Categories {
+		categoryRatio, ok := price.CategoryRatios[category]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown zone category %s", category))
+			continue
+		}
+		categoryPrice := new(big.Rat).Mul(price.SeatPrice.Rat(), categoryRatio)
+		if categoryPrice.Sign() == 0 {
+			errs = append(errs, fmt.Errorf("%s: category is free", category))
+			continue
+		}
+		ratio, err := categoryEntry.ratio(price.SeatPrice.Currency, categoryPrice)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", category, err))
+			continue
+		}
+		ratios[category] = ratio
+	}
+	if len(ratios) == 0 && len(errs) == 0 {
+		errs = append(errs, errors.New("neither multiplier nor category prices are set"))
+	}
+	return ratios, errors.Join(errs...)
+}
+
+// groupPricing validates the entry
+func (e priceListGroupEntry) groupPricing() (types.GroupPricing, error) {
+	var errs []error
+	if e.Name == "" {
+		errs = append(errs, errors.New("missing name"))
+	}
+	if e.MinSeats < 0 {
+		errs = append(errs, fmt.Errorf("invalid minimum number of seats %d", e.MinSeats))
+	}
+	for ticketType, count := range e.Tickets {
+		if !slices.Contains(types.TicketTypes, ticketType) || count < 0 {
+			errs = append(errs, fmt.Errorf("invalid tickets %d %s", count, ticketType))
+		}
+	}
+	discount, ok := new(big.Rat).SetString(e.Discount)
+	if !ok || discount.Sign() <= 0 || discount.Cmp(big.NewRat(1, 1)) > 0 {
+		errs = append(errs, fmt.Errorf("invalid discount %q, must be between 0 and 1", e.Discount))
+	}
+	return types.GroupPricing{
+		Name:     e.Name,
+		MinSeats: e.MinSeats,
+		Tickets:  e.Tickets,
+		Discount: discount,
+	}, errors.Join(errs...)
+}
+
+// demandPricing validates the entry, and sorts its tiers
+func (e priceListDemandEntry) demandPricing() (types.DemandPricing, error) {
+	if e.Scope != types.DemandScopeRoom && e.Scope != types.DemandScopeZone {
+		return types.DemandPricing{}, fmt.Errorf("invalid scope %q", e.Scope)
+	}
+	if len(e.Tiers) == 0 {
+		return types.DemandPricing{}, errors.New("no tiers")
+	}
+
+	demand := types.DemandPricing{Scope: e.Scope}
+	var errs []error
+	for i, tierEntry := range e.Tiers {
+		if tierEntry.Name == "" {
+			errs = append(errs, fmt.Errorf("tier #%d: missing name", i+1))
+		}
+		minFillRatio, ok := new(big.Rat).SetString(tierEntry.MinFillRatio)
+		if !ok || minFillRatio.Sign() < 0 || minFillRatio.Cmp(big.NewRat(1, 1)) > 0 {
+			errs = append(errs, fmt.Errorf("tier #%d: invalid fill ratio %q, must be between 0 and 1", i+1, tierEntry.MinFillRatio))
+			continue
+		}
+		multiplier, ok := new(big.Rat).SetString(tierEntry.Multiplier)
+		if !ok || multiplier.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("tier #%d: invalid multiplier %q", i+1, tierEntry.Multiplier))
+			continue
+		}
+		demand.Tiers = append(demand.Tiers, types.DemandTier{
+			Name:         tierEntry.Name,
+			MinFillRatio: minFillRatio,
+			Multiplier:   multiplier,
+		})
+	}
+
+	slices.SortFunc(demand.Tiers, func(a, b types.DemandTier) int {
+		return a.MinFillRatio.Cmp(b.MinFillRatio)
+	})
+	for i := 1; i < len(demand.Tiers); i++ {
+		if demand.Tiers[i].MinFillRatio.Cmp(demand.Tiers[i-1].MinFillRatio) == 0 {
+			errs = append(errs, fmt.Errorf("tiers %s and %s have the same fill ratio", demand.Tiers[i-1].Name, demand.Tiers[i].Name))
+		}
+	}
+	return demand, errors.Join(errs...)
+}
+
+// ratio returns the ratio of the price of the entry to the base price, in the given currency
+func (e priceListCategoryEntry) ratio(currency money.Currency, basePrice *big.Rat) (*big.Rat, error) {
+	switch {
+	case e.Price != "" && e.Multiplier != "":
+		return nil, errors.New("both price and multiplier are set")
+	case e.Price != "":
+		categoryPrice, err := money.Parse(e.Price, currency)
+		if err != nil {
+			return nil, fmt.Errorf("price: %w", err)
+		}
+		if categoryPrice.Sign() < 0 {
+			return nil, fmt.Errorf("price must not be negative, got %s", e.Price)
+		}
+		return new(big.Rat).Quo(categoryPrice.Rat(), basePrice), nil
+	case e.Multiplier != "":
+		multiplier, ok := new(big.Rat).SetString(e.Multiplier)
+		if !ok || multiplier.Sign() < 0 {
+			return nil, fmt.Errorf("invalid multiplier %q", e.Multiplier)
+		}
+		return multiplier, nil
+	default:
+		return nil, errors.New("neither price nor multiplier is set")
+	}
+}
@@ -0,0 +1,270 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestPriceList(t *testing.T) {
+	prices, err := LoadPriceList("testdata/prices.json")
+	if err != nil {
+		t.Fatalf("Failed to load price list: %v", err)
+	}
+
+	tests := []struct {
+		performanceID int64
+		seatPrice     money.Money
+	}{
+		{1, money.MustParse("35.00", money.EUR)},
+		{2, money.MustParse("28.50", money.EUR)},
+		{4, money.MustParse("32.00", money.GBP)},
+	}
+	for _, test := range tests {
+		price, err := prices.FetchPerformancePrice(test.performanceID)
+		if err != nil {
+			t.Fatalf("Failed to fetch price of performance #%d: %v", test.performanceID, err)
+		}
+		if price.SeatPrice != test.seatPrice {
+			t.Errorf("Expected performance #%d seat price to be %v, got %v", test.performanceID, test.seatPrice, price.SeatPrice)
+		}
+		// absolute premium price of performance 1 is also 1.5 times its seat price
+		if price.CategoryRatios[types.ZoneCategoryPremium].Cmp(big.NewRat(3, 2)) != 0 {
+			t.Errorf("Expected performance #%d premium ratio to be 3/2, got %v", test.performanceID, price.CategoryRatios[types.ZoneCategoryPremium])
+		}
+	}
+}
+
+func TestPriceListDemand(t *testing.T) {
+	prices, err := LoadPriceList("testdata/prices.json")
+	if err != nil {
+		t.Fatalf("Failed to load price list: %v", err)
+	}
+
+	price, err := prices.FetchPerformancePrice(1)
+	if err != nil || price.Demand != nil {
+		t.Errorf("Expected performance #1 to have fixed prices, got %v (%v)", price.Demand, err)
+	}
+
+	price, err = prices.FetchPerformancePrice(4)
+	if err != nil {
+		t.Fatalf("Failed to fetch price: %v", err)
+	}
+	if price.Demand == nil || price.Demand.Scope != types.DemandScopeZone || len(price.Demand.Tiers) != 2 {
+		t.Fatalf("Expected performance #4 to have 2 zone demand tiers, got %+v", price.Demand)
+	}
+	if price.Demand.Tiers[0].Name != "LOW" || price.Demand.Tiers[1].Name != "HIGH" {
+		t.Errorf("Expected demand tiers to be sorted by fill ratio, got %+v", price.Demand.Tiers)
+	}
+	tier, ok := price.Demand.Tier(big.NewRat(9, 10))
+	if !ok || tier.Name != "HIGH" {
+		t.Errorf("Expected HIGH tier at 90%% fill ratio, got %+v", tier)
+	}
+}
+
+func TestPriceListTicketTypes(t *testing.T) {
+	prices, err := LoadPriceList("testdata/prices.json")
+	if err != nil {
+		t.Fatalf("Failed to load price list: %v", err)
+	}
+
+	price, err := prices.FetchPerformancePrice(2)
+	if err != nil {
+		t.Fatalf("Failed to fetch price: %v", err)
+	}
+	tests := []struct {
+		ticketType types.TicketType
+		category   types.ZoneCategory
+		ratio      *big.Rat
+	}{
+		{types.TicketTypeChild, types.ZoneCategoryPremium, big.NewRat(1, 2)},
+		// absolute student price 17.10 of the 28.50 standard seat
+		{types.TicketTypeStudent, types.ZoneCategoryStandard, big.NewRat(3, 5)},
+		{types.TicketTypeStudent, types.ZoneCategoryPremium, big.NewRat(4, 5)},
+		{types.TicketTypeUnemployed, types.ZoneCategoryPremium, nil},
+	}
+	for _, test := range tests {
+		ratio, ok := price.TicketTypeRatios[test.ticketType][test.category]
+		if test.ratio == nil && ok {
+			t.Errorf("Expected %s tickets not to be sold in %s, got ratio %v", test.ticketType, test.category, ratio)
+		}
+		if test.ratio != nil && (!ok || ratio.Cmp(test.ratio) != 0) {
+			t.Errorf("Expected %s ratio in %s to be %v, got %v", test.ticketType, test.category, test.ratio, ratio)
+		}
+	}
+	family := []types.TicketType{types.TicketTypeAdult, types.TicketTypeChild, types.TicketTypeAdult, types.TicketTypeChild}
+	if len(price.Groups) != 2 || price.Groups[0].Matches(family) || !price.Groups[1].Matches(family) {
+		t.Errorf("Expected 2 adults and 2 children to match the family group only, got %+v", price.Groups)
+	}
+
+	// performances with their own entry only sell adult tickets, unless they list other ticket types
+	price, err = prices.FetchPerformancePrice(1)
+	if err != nil || len(price.TicketTypeRatios) != 0 || len(price.Groups) != 0 {
+		t.Errorf("Expected performance #1 to have no ticket types nor groups, got %+v (%v)", price, err)
+	}
+}
+
+func TestPriceListWithoutDefault(t *testing.T) {
+	list, err := parsePriceList(strings.NewReader(`{"performances": {"1": {"currency": "EUR", "seatPrice": "35.00", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}}}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse price list: %v", err)
+	}
+	prices := &PriceListDAO{priceList: list, mutex: &sync.RWMutex{}}
+	_, err = prices.FetchPerformancePrice(2)
+	if !errors.Is(err, ErrPriceNotFound) {
+		t.Errorf("Expected ErrPriceNotFound, got %v", err)
+	}
+}
+
+func TestInvalidPriceList(t *testing.T) {
+	tests := map[string]string{
+		"missing category":      `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}}}}`,
+		"unknown category":      `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}, "BALCONY": {"multiplier": "2"}}}}`,
+		"price and multiplier":  `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5", "price": "40"}}}}`,
+		"neither":               `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {}, "PREMIUM": {"multiplier": "1.5"}}}}`,
+		"sub-cent price":        `{"default": {"currency": "EUR", "seatPrice": "28.505", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}}}`,
+		"missing currency":      `{"default": {"seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}}}`,
+		"negative multiplier":   `{"performances": {"3": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "-1.5"}}}}}`,
+		"invalid demand scope":  `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}, "demand": {"scope": "ROW", "tiers": [{"name": "LOW", "minFillRatio": "0", "multiplier": "0.9"}]}}}`,
+		"no demand tiers":       `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}, "demand": {"scope": "ROOM", "tiers": []}}}`,
+		"fill ratio above 1":    `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}, "demand": {"scope": "ROOM", "tiers": [{"name": "FULL", "minFillRatio": "1.5", "multiplier": "2"}]}}}`,
+		"duplicate fill ratios": `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}, "demand": {"scope": "ROOM", "tiers": [{"name": "A", "minFillRatio": "0.5", "multiplier": "1.1"}, {"name": "B", "minFillRatio": "1/2", "multiplier": "1.2"}]}}}`,
+		"unknown ticket type":   `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}, "ticketTypes": {"TODDLER": "0"}}}`,
+		"invalid ticket price":  `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}, "ticketTypes": {"STUDENT": {"BALCONY": {"price": "10.00"}}}}}`,
+		"invalid group":         `{"default": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}, "groups": [{"name": "GROUP", "minSeats": 6, "discount": "1.5"}]}}`,
+		"invalid performanceID": `{"performances": {"three": {"currency": "EUR", "seatPrice": "28.50", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}}}}`,
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parsePriceList(strings.NewReader(input))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if name == "missing category" && !errors.Is(err, ErrMissingCategory) {
+				t.Errorf("Expected ErrMissingCategory, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPriceListReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	writePriceList(t, path, "30.00", time.Now().Add(-time.Hour))
+	prices, err := LoadPriceList(path)
+	if err != nil {
+		t.Fatalf("Failed to load price list: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 10)
+	go prices.Watch(ctx, time.Millisecond, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+
+	// an invalid price list is reported, and previous prices remain in effect
+	err = os.WriteFile(path, []byte(`{"default": {"currency": "EUR", "seatPrice": "40.00", "categories": {}}}`), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write price list: %v", err)
+	}
+	// the file may be read while it is being written, until it is complete
+	timeout := time.After(5 * time.Second)
+	for reported := false; !reported; {
+		select {
+		case err := <-errs:
+			reported = errors.Is(err, ErrMissingCategory)
+		case <-timeout:
+			t.Fatal("Invalid price list was not reported")
+		}
+	}
+	expectSeatPrice(t, prices, "30.00")
+
+	writePriceList(t, path, "40.00", time.Now().Add(time.Hour))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		price, err := prices.FetchPerformancePrice(1)
+		if err == nil && price.SeatPrice == money.MustParse("40.00", money.EUR) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Price list was not reloaded, seat price is %v", price.SeatPrice)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPriceListWatchReportsEachInvalidVersionOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	writePriceList(t, path, "30.00", time.Now().Add(-time.Hour))
+	prices, err := LoadPriceList(path)
+	if err != nil {
+		t.Fatalf("Failed to load price list: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 100)
+	go prices.Watch(ctx, time.Millisecond, func(err error) {
+		errs <- err
+	})
+
+	for i, modTime := range []time.Time{time.Now().Add(time.Hour), time.Now().Add(2 * time.Hour)} {
+		err = os.WriteFile(path, []byte(`{"default": `), 0o644)
+		if err == nil {
+			err = os.Chtimes(path, modTime, modTime)
+		}
+		if err != nil {
+			t.Fatalf("Failed to write price list: %v", err)
+		}
+		select {
+		case <-errs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Invalid price list #%d was not reported", i+1)
+		}
+		// the same version is not read again
+		time.Sleep(50 * time.Millisecond)
+		if len(errs) > 0 {
+			t.Fatalf("Expected invalid price list #%d to be reported once, got %d more errors", i+1, len(errs))
+		}
+	}
+	expectSeatPrice(t, prices, "30.00")
+}
+
+func writePriceList(t *testing.T, path string, seatPrice string, modTime time.Time) {
+	t.Helper()
+
+	content := `{"default": {"currency": "EUR", "seatPrice": "` + seatPrice + `", "categories": {"STANDARD": {"multiplier": "1"}, "PREMIUM": {"multiplier": "1.5"}}}}`
+	err := os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("Failed to write price list: %v", err)
+	}
+	// make sure the modification is noticed, whatever the resolution of the file system timestamps
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatalf("Failed to change price list modification time: %v", err)
+	}
+}
+
+func expectSeatPrice(t *testing.T, prices *PriceListDAO, expected string) {
+	t.Helper()
+
+	price, err := prices.FetchPerformancePrice(1)
+	if err != nil {
+		t.Fatalf("Failed to fetch price: %v", err)
+	}
+	if price.SeatPrice != money.MustParse(expected, money.EUR) {
+		t.Errorf("Expected seat price %s, got %v", expected, price.SeatPrice)
+	}
+}
@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// TheaterRoomRepository stores the room map/topology of each performance, with the status of its seats
+type TheaterRoomRepository interface {
+	FetchTheaterRoom(performanceID int64) (types.TheaterRoom, error)
+	SaveTheaterRoom(performanceID int64, room types.TheaterRoom) error
+	SaveSeats(performanceID int64, seatsIDs []string, status types.SeatStatus) error
+	// HoldSeats marks the given seats BOOKING_PENDING, only if every one of them is currently FREE:
+	// otherwise, no seat is changed and a *SeatConflictError is returned
+	HoldSeats(performanceID int64, seatsIDs []string) error
+}
+
+// SeatConflictError reports the seats which could not be held because they are not free (or do not exist)
+type SeatConflictError struct {
+	PerformanceID int64
+	SeatsIDs      []string
+}
+
+func (e *SeatConflictError) Error() string {
+	return fmt.Sprintf("seats %s of performance %d are not free", strings.Join(e.SeatsIDs, ", "), e.PerformanceID)
+}
+
+// ReservationRepository stores reservations by ID.
+// Find returns a nil reservation and no error when the reservation does not exist.
+type ReservationRepository interface {
+	Update(reservation types.Reservation) error
+	Find(reservationID int64) (*types.Reservation, error)
+	FindByStatus(status types.ReservationStatus) ([]types.Reservation, error)
+	// FindByVoucherCode returns the reservations which used the voucher code, whether the use was released or not
+	FindByVoucherCode(code string) ([]types.Reservation, error)
+	// FindByPerformance returns the reservations of the performance, whatever their status
+	FindByPerformance(performanceID int64) ([]types.Reservation, error)
+	// LastReservationID returns the highest stored reservation ID, 0 when there is none
+	LastReservationID() (int64, error)
+}
+
+// Transactor runs fn within a transaction: the writes made through the repositories given to fn
+// are committed together when fn returns nil, and rolled back when it returns an error.
+// Repositories must not be used outside fn while it runs.
+type Transactor interface {
+	Transaction(fn func(rooms TheaterRoomRepository, reservations ReservationRepository, ledger LoyaltyLedgerRepository) error) error
+}
+
+// PerformancePriceRepository provides the seat prices of each performance, for each zone category
+type PerformancePriceRepository interface {
+	FetchPerformancePrice(performanceID int64) (types.PerformancePrice, error)
+}
+
+// VoucherProgramRepository provides the discount ratio granted by the voucher program at a given date
+type VoucherProgramRepository interface {
+	FetchVoucherProgram(reservationDate time.Time) (*big.Rat, error)
+}
+
+// VoucherRepository provides the voucher codes customers can present when booking.
+// FetchVoucher returns a nil voucher and no error when the code does not exist.
+type VoucherRepository interface {
+	FetchVoucher(code string) (*types.Voucher, error)
+	SaveVoucher(voucher types.Voucher) error
+}
+
+// CustomerSubscriptionRepository tells which tier of the fidelity program a customer has subscribed to
+type CustomerSubscriptionRepository interface {
+	FetchCustomerSubscription(customerID int64) (types.SubscriptionTier, error)
+}
+
+// ConcessionEligibilityRepository tells whether customers have proven their eligibility to concession ticket types
+type ConcessionEligibilityRepository interface {
+	FetchEligibility(customerID int64, ticketType types.TicketType) (types.Eligibility, error)
+}
+
+// LoyaltyLedgerRepository stores the loyalty points ledger of each customer, it is only appended to.
+// Redeem entries fail with ErrInsufficientPoints when the balance of the customer would become negative.
+type LoyaltyLedgerRepository interface {
+	AppendLoyaltyEntry(entry types.LoyaltyEntry) error
+	// FetchLoyaltyEntries returns the entries of the customer, oldest first
+	FetchLoyaltyEntries(customerID int64) ([]types.LoyaltyEntry, error)
+}
+
+// the in-memory DAOs are the default implementations
+var (
+	_ TheaterRoomRepository           = (*TheaterRoomsDAO)(nil)
+	_ ReservationRepository           = (*ReservationDAO)(nil)
+	_ PerformancePriceRepository      = (*PerformancePriceDAO)(nil)
+	_ PerformancePriceRepository      = (*PriceListDAO)(nil)
+	_ VoucherProgramRepository        = (*VoucherProgramDAO)(nil)
+	_ VoucherRepository               = (*VoucherDAO)(nil)
+	_ CustomerSubscriptionRepository  = (*CustomerSubscriptionDAO)(nil)
+	_ LoyaltyLedgerRepository         = (*LoyaltyLedgerDAO)(nil)
+	_ ConcessionEligibilityRepository = (*ConcessionEligibilityDAO)(nil)
+	_ Transactor                      = (*MemoryTransactor)(nil)
+
+	_ TheaterRoomRepository   = (*SQLiteStore)(nil)
+	_ ReservationRepository   = (*SQLiteStore)(nil)
+	_ LoyaltyLedgerRepository = (*SQLiteStore)(nil)
+	_ Transactor              = (*SQLiteStore)(nil)
+)
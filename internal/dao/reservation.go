@@ -1,6 +1,9 @@
 package dao
 
 import (
+	"cmp"
+	"maps"
+	"slices"
 	"sync"
 
 	"github.com/benoitmasson/theater-reservation-kata/internal/types"
@@ -11,14 +14,14 @@ type ReservationDAO struct {
 	mutex          *sync.RWMutex
 }
 
-func NewReservationDAO() ReservationDAO {
-	return ReservationDAO{
+func NewReservationDAO() *ReservationDAO {
+	return &ReservationDAO{
 		reservationMap: make(map[int64]*types.Reservation),
 		mutex:          &sync.RWMutex{},
 	}
 }
 
-func (dao *ReservationDAO) Update(reservation types.Reservation) {
+func (dao *ReservationDAO) Update(reservation types.Reservation) error {
 	dao.mutex.Lock()
 	defer dao.mutex.Unlock()
 
@@ -26,14 +29,94 @@ func (dao *ReservationDAO) Update(reservation types.Reservation) {
 		dao.reservationMap = make(map[int64]*types.Reservation)
 	}
 	dao.reservationMap[reservation.ReservationID] = &reservation
+	return nil
 }
 
-func (dao *ReservationDAO) Find(reservationID int64) *types.Reservation {
+func (dao *ReservationDAO) Find(reservationID int64) (*types.Reservation, error) {
 	dao.mutex.RLock()
 	defer dao.mutex.RUnlock()
 
 	if dao.reservationMap == nil {
-		return nil
+		return nil, nil
 	}
-	return dao.reservationMap[reservationID]
+	reservation, ok := dao.reservationMap[reservationID]
+	if !ok {
+		return nil, nil
+	}
+	// callers get a copy, which they must Update to change the stored reservation
+	found := *reservation
+	return &found, nil
+}
+
+func (dao *ReservationDAO) FindByStatus(status types.ReservationStatus) ([]types.Reservation, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	var reservations []types.Reservation
+	for _, reservation := range dao.reservationMap {
+		if reservation.Status == status {
+			reservations = append(reservations, *reservation)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b types.Reservation) int {
+		return cmp.Compare(a.ReservationID, b.ReservationID)
+	})
+	return reservations, nil
+}
+
+func (dao *ReservationDAO) FindByVoucherCode(code string) ([]types.Reservation, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	var reservations []types.Reservation
+	for _, reservation := range dao.reservationMap {
+		if reservation.Voucher != nil && reservation.Voucher.Code == code {
+			reservations = append(reservations, *reservation)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b types.Reservation) int {
+		return cmp.Compare(a.ReservationID, b.ReservationID)
+	})
+	return reservations, nil
+}
+
+func (dao *ReservationDAO) FindByPerformance(performanceID int64) ([]types.Reservation, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	var reservations []types.Reservation
+	for _, reservation := range dao.reservationMap {
+		if reservation.PerformanceID == performanceID {
+			reservations = append(reservations, *reservation)
+		}
+	}
+	slices.SortFunc(reservations, func(a, b types.Reservation) int {
+		return cmp.Compare(a.ReservationID, b.ReservationID)
+	})
+	return reservations, nil
+}
+
+func (dao *ReservationDAO) snapshot() func() {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	// stored reservations are replaced on update, never modified
+	reservationMap := maps.Clone(dao.reservationMap)
+	return func() {
+		dao.mutex.Lock()
+		defer dao.mutex.Unlock()
+
+		dao.reservationMap = reservationMap
+	}
+}
+
+func (dao *ReservationDAO) LastReservationID() (int64, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	var lastID int64
+	for reservationID := range dao.reservationMap {
+		lastID = max(lastID, reservationID)
+	}
+	return lastID, nil
 }
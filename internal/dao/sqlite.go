@@ -0,0 +1,341 @@
+package dao
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// SQLiteStore persists theater rooms, reservations and the loyalty points ledger in an embedded SQLite database file.
+type SQLiteStore struct {
+	sqliteRepository
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens (or creates) the database at path, and brings its schema up to date.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := url.URL{
+		Scheme: "file",
+		// SQLite decodes the %-escapes of URI filenames, "?" and "#" would start the query or the fragment
+		Opaque:   uriFilenameEscaper.Replace(path),
+		RawQuery: "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)",
+	}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	// SQLite allows a single writer: serializing connections avoids "database is locked" errors
+	db.SetMaxOpenConns(1)
+
+	err = migrate(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{
+		sqliteRepository: sqliteRepository{q: db},
+		db:               db,
+	}, nil
+}
+
+var uriFilenameEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// Transaction runs fn within a database transaction. The store has a single connection, held by the transaction:
+// fn must only use the repositories it is given, calling the methods of the store itself would wait forever.
+func (s *SQLiteStore) Transaction(fn func(rooms TheaterRoomRepository, reservations ReservationRepository, ledger LoyaltyLedgerRepository) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback() // no-op once committed
+
+	repository := &sqliteRepository{q: tx}
+	err = fn(repository, repository, repository)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// HoldSeats runs within its own transaction, so that seats cannot be taken between the check and the update
+func (s *SQLiteStore) HoldSeats(performanceID int64, seatsIDs []string) error {
+	return s.Transaction(func(rooms TheaterRoomRepository, _ ReservationRepository, _ LoyaltyLedgerRepository) error {
+		return rooms.HoldSeats(performanceID, seatsIDs)
+	})
+}
+
+// AppendLoyaltyEntry runs within its own transaction, so that points cannot be spent between the balance check and the insert
+func (s *SQLiteStore) AppendLoyaltyEntry(entry types.LoyaltyEntry) error {
+	return s.Transaction(func(_ TheaterRoomRepository, _ ReservationRepository, ledger LoyaltyLedgerRepository) error {
+		return ledger.AppendLoyaltyEntry(entry)
+	})
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// sqliteRepository implements the repositories over a database connection or transaction
+type sqliteRepository struct {
+	q querier
+}
+
+func (r *sqliteRepository) FetchTheaterRoom(performanceID int64) (types.TheaterRoom, error) {
+	rows, err := r.q.Query(`SELECT zone_index, zone_category, row_index, seat_id, status FROM seats
+		WHERE performance_id = ?
+		ORDER BY zone_index, row_index, seat_index`, performanceID)
+	if err != nil {
+		return types.TheaterRoom{}, err
+	}
+	defer rows.Close()
+
+	var room types.TheaterRoom
+	for rows.Next() {
+		var zoneIndex, rowIndex int
+		var category types.ZoneCategory
+		var seat types.Seat
+		err = rows.Scan(&zoneIndex, &category, &rowIndex, &seat.SeatID, &seat.Status)
+		if err != nil {
+			return types.TheaterRoom{}, err
+		}
+
+		for len(room.Zones) <= zoneIndex {
+			room.Zones = append(room.Zones, types.Zone{})
+		}
+		zone := &room.Zones[zoneIndex]
+		zone.Category = category
+		for len(zone.Rows) <= rowIndex {
+			zone.Rows = append(zone.Rows, types.Row{})
+		}
+		zone.Rows[rowIndex].Seats = append(zone.Rows[rowIndex].Seats, seat)
+	}
+
+	return room, rows.Err()
+}
+
+func (r *sqliteRepository) SaveTheaterRoom(performanceID int64, room types.TheaterRoom) error {
+	_, err := r.q.Exec(`DELETE FROM seats WHERE performance_id = ?`, performanceID)
+	if err != nil {
+		return err
+	}
+
+	for zoneIndex, zone := range room.Zones {
+		for rowIndex, row := range zone.Rows {
+			for seatIndex, seat := range row.Seats {
+				_, err = r.q.Exec(`INSERT INTO seats (performance_id, seat_id, zone_index, zone_category, row_index, seat_index, status)
+					VALUES (?, ?, ?, ?, ?, ?, ?)`,
+					performanceID, seat.SeatID, zoneIndex, zone.Category, rowIndex, seatIndex, seat.Status)
+				if err != nil {
+					return fmt.Errorf("insert seat %s: %w", seat.SeatID, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *sqliteRepository) SaveSeats(performanceID int64, seatsIDs []string, status types.SeatStatus) error {
+	if len(seatsIDs) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(seatsIDs)+2)
+	args = append(args, status, performanceID)
+	for _, seatID := range seatsIDs {
+		args = append(args, seatID)
+	}
+	_, err := r.q.Exec(`UPDATE seats SET status = ? WHERE performance_id = ? AND seat_id IN (`+placeholders(len(seatsIDs))+`)`, args...)
+	return err
+}
+
+func (r *sqliteRepository) HoldSeats(performanceID int64, seatsIDs []string) error {
+	if len(seatsIDs) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(seatsIDs)+2)
+	args = append(args, performanceID, types.SeatStatusFree)
+	for _, seatID := range seatsIDs {
+		args = append(args, seatID)
+	}
+	rows, err := r.q.Query(`SELECT seat_id FROM seats WHERE performance_id = ? AND status = ? AND seat_id IN (`+placeholders(len(seatsIDs))+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	freeSeats := make(map[string]bool, len(seatsIDs))
+	for rows.Next() {
+		var seatID string
+		err = rows.Scan(&seatID)
+		if err != nil {
+			return err
+		}
+		freeSeats[seatID] = true
+	}
+	err = rows.Err()
+	if err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for _, seatID := range seatsIDs {
+		if !freeSeats[seatID] {
+			conflicts = append(conflicts, seatID)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &SeatConflictError{PerformanceID: performanceID, SeatsIDs: conflicts}
+	}
+
+	return r.SaveSeats(performanceID, seatsIDs, types.SeatStatusBookingPending)
+}
+
+func (r *sqliteRepository) Update(reservation types.Reservation) error {
+	document, err := json.Marshal(reservation)
+	if err != nil {
+		return fmt.Errorf("encode reservation: %w", err)
+	}
+
+	var voucherCode sql.NullString
+	if reservation.Voucher != nil {
+		voucherCode = sql.NullString{String: reservation.Voucher.Code, Valid: true}
+	}
+
+	_, err = r.q.Exec(`INSERT INTO reservations (reservation_id, performance_id, status, voucher_code, document) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (reservation_id) DO UPDATE SET performance_id = excluded.performance_id, status = excluded.status,
+			voucher_code = excluded.voucher_code, document = excluded.document`,
+		reservation.ReservationID, reservation.PerformanceID, reservation.Status, voucherCode, document)
+	return err
+}
+
+func (r *sqliteRepository) Find(reservationID int64) (*types.Reservation, error) {
+	var document []byte
+	err := r.q.QueryRow(`SELECT document FROM reservations WHERE reservation_id = ?`, reservationID).Scan(&document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation types.Reservation
+	err = json.Unmarshal(document, &reservation)
+	if err != nil {
+		return nil, fmt.Errorf("decode reservation %d: %w", reservationID, err)
+	}
+	return &reservation, nil
+}
+
+func (r *sqliteRepository) FindByStatus(status types.ReservationStatus) ([]types.Reservation, error) {
+	return r.findReservations(`SELECT document FROM reservations WHERE status = ? ORDER BY reservation_id`, status)
+}
+
+func (r *sqliteRepository) FindByVoucherCode(code string) ([]types.Reservation, error) {
+	return r.findReservations(`SELECT document FROM reservations WHERE voucher_code = ? ORDER BY reservation_id`, code)
+}
+
+func (r *sqliteRepository) FindByPerformance(performanceID int64) ([]types.Reservation, error) {
+	return r.findReservations(`SELECT document FROM reservations WHERE performance_id = ? ORDER BY reservation_id`, performanceID)
+}
+
+// findReservations decodes the reservation documents selected by query
+func (r *sqliteRepository) findReservations(query string, args ...any) ([]types.Reservation, error) {
+	rows, err := r.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []types.Reservation
+	for rows.Next() {
+		var document []byte
+		err = rows.Scan(&document)
+		if err != nil {
+			return nil, err
+		}
+		var reservation types.Reservation
+		err = json.Unmarshal(document, &reservation)
+		if err != nil {
+			return nil, fmt.Errorf("decode reservation: %w", err)
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, rows.Err()
+}
+
+func (r *sqliteRepository) LastReservationID() (int64, error) {
+	var lastID int64
+	err := r.q.QueryRow(`SELECT COALESCE(MAX(reservation_id), 0) FROM reservations`).Scan(&lastID)
+	return lastID, err
+}
+
+func (r *sqliteRepository) AppendLoyaltyEntry(entry types.LoyaltyEntry) error {
+	if entry.Kind == types.LoyaltyEntryRedeem {
+		var balance int64
+		err := r.q.QueryRow(`SELECT COALESCE(SUM(points), 0) FROM loyalty_entries WHERE customer_id = ?`, entry.CustomerID).Scan(&balance)
+		if err != nil {
+			return err
+		}
+		if balance+entry.Points < 0 {
+			return ErrInsufficientPoints
+		}
+	}
+
+	_, err := r.q.Exec(`INSERT INTO loyalty_entries (customer_id, reservation_id, kind, points, at) VALUES (?, ?, ?, ?, ?)`,
+		entry.CustomerID, entry.ReservationID, entry.Kind, entry.Points, entry.At.Format(time.RFC3339Nano))
+	return err
+}
+
+func (r *sqliteRepository) FetchLoyaltyEntries(customerID int64) ([]types.LoyaltyEntry, error) {
+	rows, err := r.q.Query(`SELECT customer_id, reservation_id, kind, points, at FROM loyalty_entries
+		WHERE customer_id = ?
+		ORDER BY entry_id`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []types.LoyaltyEntry
+	for rows.Next() {
+		var entry types.LoyaltyEntry
+		var at string
+		err = rows.Scan(&entry.CustomerID, &entry.ReservationID, &entry.Kind, &entry.Points, &at)
+		if err != nil {
+			return nil, err
+		}
+		entry.At, err = time.Parse(time.RFC3339Nano, at)
+		if err != nil {
+			return nil, fmt.Errorf("decode loyalty entry time: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// placeholders returns n comma-separated "?" SQL placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
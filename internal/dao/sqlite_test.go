@@ -0,0 +1,228 @@
+package dao
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func openTestStore(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+
+	store, err := OpenSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func seatStatus(t *testing.T, rooms TheaterRoomRepository, performanceID int64, seatID string) types.SeatStatus {
+	t.Helper()
+
+	room, err := rooms.FetchTheaterRoom(performanceID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	for _, zone := range room.Zones {
+		for _, row := range zone.Rows {
+			for _, seat := range row.Seats {
+				if seat.SeatID == seatID {
+					return seat.Status
+				}
+			}
+		}
+	}
+	t.Fatalf("Seat %s not found", seatID)
+	return ""
+}
+
+func TestSQLiteStorePersistsBookings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theater.db")
+	store := openTestStore(t, path)
+
+	err := store.SaveTheaterRoom(1, fetchRoomForPerformance1())
+	if err != nil {
+		t.Fatalf("Failed to save room: %v", err)
+	}
+	err = store.Transaction(func(rooms TheaterRoomRepository, reservations ReservationRepository, _ LoyaltyLedgerRepository) error {
+		err := rooms.SaveSeats(1, []string{"C1", "C2"}, types.SeatStatusBookingPending)
+		if err != nil {
+			return err
+		}
+		return reservations.Update(types.Reservation{
+			ReservationID: 42,
+			PerformanceID: 1,
+			Status:        types.ReservationStatusPending,
+			Seats:         []string{"C1", "C2"},
+		})
+	})
+	if err != nil {
+		t.Fatalf("Failed to save booking: %v", err)
+	}
+	store.Close()
+
+	// data and schema survive reopening
+	store = openTestStore(t, path)
+	room, err := store.FetchTheaterRoom(1)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	if len(room.Zones) != 2 || len(room.Zones[0].Rows) != 7 || room.Zones[1].Category != types.ZoneCategoryPremium {
+		t.Errorf("Unexpected room topology: %v", room)
+	}
+	if status := seatStatus(t, store, 1, "C2"); status != types.SeatStatusBookingPending {
+		t.Errorf("Expected seat C2 to be %s, got %s", types.SeatStatusBookingPending, status)
+	}
+	reservation, err := store.Find(42)
+	if err != nil || reservation == nil {
+		t.Fatalf("Failed to find reservation: %v", err)
+	}
+	if reservation.Status != types.ReservationStatusPending || !slices.Equal(reservation.Seats, []string{"C1", "C2"}) {
+		t.Errorf("Unexpected reservation: %v", reservation)
+	}
+	lastID, err := store.LastReservationID()
+	if err != nil || lastID != 42 {
+		t.Errorf("Expected last reservation ID 42, got %d (%v)", lastID, err)
+	}
+}
+
+func TestSQLiteStoreRollsBackFailedTransaction(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "theater.db"))
+
+	err := store.SaveTheaterRoom(1, fetchRoomForPerformance1())
+	if err != nil {
+		t.Fatalf("Failed to save room: %v", err)
+	}
+	errFailure := errors.New("failure")
+	err = store.Transaction(func(rooms TheaterRoomRepository, reservations ReservationRepository, _ LoyaltyLedgerRepository) error {
+		err := rooms.SaveSeats(1, []string{"C1"}, types.SeatStatusBookingPending)
+		if err != nil {
+			return err
+		}
+		err = reservations.Update(types.Reservation{ReservationID: 42, PerformanceID: 1, Status: types.ReservationStatusPending})
+		if err != nil {
+			return err
+		}
+		return errFailure
+	})
+	if !errors.Is(err, errFailure) {
+		t.Fatalf("Expected transaction failure, got %v", err)
+	}
+
+	if status := seatStatus(t, store, 1, "C1"); status != types.SeatStatusFree {
+		t.Errorf("Expected seat C1 to be %s, got %s", types.SeatStatusFree, status)
+	}
+	reservation, err := store.Find(42)
+	if err != nil || reservation != nil {
+		t.Errorf("Expected no reservation, got %v (%v)", reservation, err)
+	}
+}
+
+func TestSQLiteStoreFindByVoucherCode(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "theater.db"))
+
+	for _, reservation := range []types.Reservation{
+		{ReservationID: 1, Status: types.ReservationStatusPending, Voucher: &types.VoucherRedemption{Code: "SPRING23"}},
+		{ReservationID: 2, Status: types.ReservationStatusPending},
+		{ReservationID: 3, Status: types.ReservationStatusCancelled, Voucher: &types.VoucherRedemption{Code: "SPRING23", ReleasedAt: time.Now()}},
+		{ReservationID: 4, Status: types.ReservationStatusPending, Voucher: &types.VoucherRedemption{Code: "CICDFAN"}},
+	} {
+		err := store.Update(reservation)
+		if err != nil {
+			t.Fatalf("Failed to save reservation: %v", err)
+		}
+	}
+
+	reservations, err := store.FindByVoucherCode("SPRING23")
+	if err != nil {
+		t.Fatalf("Failed to find reservations: %v", err)
+	}
+	if len(reservations) != 2 || reservations[0].ReservationID != 1 || reservations[1].ReservationID != 3 || reservations[1].Voucher.IsActive() {
+		t.Errorf("Unexpected reservations: %+v", reservations)
+	}
+}
+
+func TestSQLiteStoreFindByPerformance(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), "theater.db"))
+
+	for _, reservation := range []types.Reservation{
+		{ReservationID: 1, PerformanceID: 1, Status: types.ReservationStatusConfirmed},
+		{ReservationID: 2, PerformanceID: 2, Status: types.ReservationStatusPending},
+		{ReservationID: 3, PerformanceID: 1, Status: types.ReservationStatusCancelled},
+	} {
+		err := store.Update(reservation)
+		if err != nil {
+			t.Fatalf("Failed to save reservation: %v", err)
+		}
+	}
+
+	reservations, err := store.FindByPerformance(1)
+	if err != nil {
+		t.Fatalf("Failed to find reservations: %v", err)
+	}
+	if len(reservations) != 2 || reservations[0].ReservationID != 1 || reservations[1].ReservationID != 3 {
+		t.Errorf("Unexpected reservations: %+v", reservations)
+	}
+}
+
+func TestSQLiteStoreLoyaltyLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theater.db")
+	store := openTestStore(t, path)
+	at := time.Date(2023, time.April, 1, 10, 0, 0, 0, time.UTC)
+
+	err := store.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 1, ReservationID: 1, Kind: types.LoyaltyEntryEarn, Points: 50, At: at})
+	if err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+	err = store.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 1, ReservationID: 2, Kind: types.LoyaltyEntryRedeem, Points: -60, At: at})
+	if !errors.Is(err, ErrInsufficientPoints) {
+		t.Errorf("Expected ErrInsufficientPoints, got %v", err)
+	}
+
+	// entries are rolled back with the other writes of a failed transaction
+	errFailure := errors.New("failure")
+	err = store.Transaction(func(_ TheaterRoomRepository, _ ReservationRepository, ledger LoyaltyLedgerRepository) error {
+		err := ledger.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 1, ReservationID: 3, Kind: types.LoyaltyEntryRedeem, Points: -20, At: at})
+		if err != nil {
+			return err
+		}
+		return errFailure
+	})
+	if !errors.Is(err, errFailure) {
+		t.Fatalf("Expected transaction failure, got %v", err)
+	}
+	store.Close()
+
+	// entries survive reopening
+	store = openTestStore(t, path)
+	entries, err := store.FetchLoyaltyEntries(1)
+	if err != nil {
+		t.Fatalf("Failed to fetch entries: %v", err)
+	}
+	expected := []types.LoyaltyEntry{{CustomerID: 1, ReservationID: 1, Kind: types.LoyaltyEntryEarn, Points: 50, At: at}}
+	if len(entries) != 1 || entries[0] != expected[0] {
+		t.Errorf("Expected entries %+v, got %+v", expected, entries)
+	}
+}
+
+func TestOpenSQLiteStoreEscapesPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a?b#c%20d")
+	err := os.Mkdir(dir, 0o755)
+	if err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	path := filepath.Join(dir, "theater.db")
+	store := openTestStore(t, path)
+	store.Close()
+
+	_, err = os.Stat(path)
+	if err != nil {
+		t.Errorf("Expected database at %s: %v", path, err)
+	}
+}
@@ -12,8 +12,8 @@ type TheaterRoomsDAO struct {
 	mutex           *sync.RWMutex
 }
 
-func NewTheaterRoomsDAO() TheaterRoomsDAO {
-	dao := TheaterRoomsDAO{
+func NewTheaterRoomsDAO() *TheaterRoomsDAO {
+	dao := &TheaterRoomsDAO{
 		theaterRoomMaps: make(map[int64]types.TheaterRoom, 3),
 		mutex:           &sync.RWMutex{},
 	}
@@ -26,21 +26,40 @@ func NewTheaterRoomsDAO() TheaterRoomsDAO {
 }
 
 // FetchTheaterRoom simulates a room map/topology repository
-func (dao *TheaterRoomsDAO) FetchTheaterRoom(performanceID int64) types.TheaterRoom {
+func (dao *TheaterRoomsDAO) FetchTheaterRoom(performanceID int64) (types.TheaterRoom, error) {
 	dao.mutex.RLock()
 	defer dao.mutex.RUnlock()
 
-	return dao.theaterRoomMaps[performanceID]
+	return cloneTheaterRoom(dao.theaterRoomMaps[performanceID]), nil
 }
 
-func (dao *TheaterRoomsDAO) SaveTheaterRoom(performanceID int64, room types.TheaterRoom) {
+// cloneTheaterRoom returns a deep copy of room, so that callers get a snapshot
+// which is not altered by later seat updates
+func cloneTheaterRoom(room types.TheaterRoom) types.TheaterRoom {
+	clone := types.TheaterRoom{
+		Zones: make([]types.Zone, len(room.Zones)),
+	}
+	for i, zone := range room.Zones {
+		clone.Zones[i] = types.Zone{
+			Category: zone.Category,
+			Rows:     make([]types.Row, len(zone.Rows)),
+		}
+		for j, row := range zone.Rows {
+			clone.Zones[i].Rows[j].Seats = slices.Clone(row.Seats)
+		}
+	}
+	return clone
+}
+
+func (dao *TheaterRoomsDAO) SaveTheaterRoom(performanceID int64, room types.TheaterRoom) error {
 	dao.mutex.Lock()
 	defer dao.mutex.Unlock()
 
 	dao.theaterRoomMaps[performanceID] = room
+	return nil
 }
 
-func (dao *TheaterRoomsDAO) SaveSeats(performanceID int64, seatsIDs []string, status types.SeatStatus) {
+func (dao *TheaterRoomsDAO) SaveSeats(performanceID int64, seatsIDs []string, status types.SeatStatus) error {
 	dao.mutex.Lock()
 	defer dao.mutex.Unlock()
 
@@ -57,6 +76,57 @@ func (dao *TheaterRoomsDAO) SaveSeats(performanceID int64, seatsIDs []string, st
 			}
 		}
 	}
+	return nil
+}
+
+func (dao *TheaterRoomsDAO) HoldSeats(performanceID int64, seatsIDs []string) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	room := dao.theaterRoomMaps[performanceID]
+	freeSeats := make(map[string]*types.Seat, len(seatsIDs))
+	for _, zone := range room.Zones {
+		for _, row := range zone.Rows {
+			for k := range row.Seats {
+				seat := &row.Seats[k]
+				if seat.Status == types.SeatStatusFree && slices.Contains(seatsIDs, seat.SeatID) {
+					freeSeats[seat.SeatID] = seat
+				}
+			}
+		}
+	}
+
+	var conflicts []string
+	for _, seatID := range seatsIDs {
+		if freeSeats[seatID] == nil {
+			conflicts = append(conflicts, seatID)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &SeatConflictError{PerformanceID: performanceID, SeatsIDs: conflicts}
+	}
+
+	for _, seat := range freeSeats {
+		seat.Status = types.SeatStatusBookingPending
+	}
+	return nil
+}
+
+func (dao *TheaterRoomsDAO) snapshot() func() {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	// seats are updated in place, rooms are copied
+	theaterRoomMaps := make(map[int64]types.TheaterRoom, len(dao.theaterRoomMaps))
+	for performanceID, room := range dao.theaterRoomMaps {
+		theaterRoomMaps[performanceID] = cloneTheaterRoom(room)
+	}
+	return func() {
+		dao.mutex.Lock()
+		defer dao.mutex.Unlock()
+
+		dao.theaterRoomMaps = theaterRoomMaps
+	}
 }
 
 func fetchRoomForPerformance1() types.TheaterRoom {
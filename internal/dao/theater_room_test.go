@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestHoldSeats(t *testing.T) {
+	implementations := map[string]func(t *testing.T) TheaterRoomRepository{
+		"memory": func(t *testing.T) TheaterRoomRepository {
+			return NewTheaterRoomsDAO()
+		},
+		"sqlite": func(t *testing.T) TheaterRoomRepository {
+			store := openTestStore(t, filepath.Join(t.TempDir(), "theater.db"))
+			err := store.SaveTheaterRoom(1, fetchRoomForPerformance1())
+			if err != nil {
+				t.Fatalf("Failed to save room: %v", err)
+			}
+			return store
+		},
+	}
+
+	for name, newRepository := range implementations {
+		t.Run(name, func(t *testing.T) {
+			rooms := newRepository(t)
+
+			err := rooms.HoldSeats(1, []string{"A5", "A6"})
+			if err != nil {
+				t.Fatalf("Failed to hold free seats: %v", err)
+			}
+
+			// A1 is booked, A6 is now pending and Z9 does not exist
+			err = rooms.HoldSeats(1, []string{"A1", "A6", "A7", "Z9"})
+			var conflictErr *SeatConflictError
+			if !errors.As(err, &conflictErr) {
+				t.Fatalf("Expected a seat conflict, got %v", err)
+			}
+			if !slices.Equal(conflictErr.SeatsIDs, []string{"A1", "A6", "Z9"}) {
+				t.Errorf("Unexpected conflicting seats: %v", conflictErr.SeatsIDs)
+			}
+
+			for seatID, expected := range map[string]types.SeatStatus{
+				"A1": types.SeatStatusBooked,
+				"A5": types.SeatStatusBookingPending,
+				"A6": types.SeatStatusBookingPending,
+				"A7": types.SeatStatusFree,
+			} {
+				if status := seatStatus(t, rooms, 1, seatID); status != expected {
+					t.Errorf("Expected seat %s to be %s, got %s", seatID, expected, status)
+				}
+			}
+		})
+	}
+}
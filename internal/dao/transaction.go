@@ -0,0 +1,49 @@
+package dao
+
+import "sync"
+
+// MemoryTransactor runs transactions over in-memory repositories.
+//
+// Transactions are serialized. The repositories which can take a snapshot of their data, as the DAOs of this package,
+// are restored when fn fails; the writes made meanwhile outside the transactor are lost with the ones of fn.
+type MemoryTransactor struct {
+	rooms        TheaterRoomRepository
+	reservations ReservationRepository
+	ledger       LoyaltyLedgerRepository
+	mutex        *sync.Mutex
+}
+
+// snapshotter is implemented by the in-memory repositories which MemoryTransactor can roll back
+type snapshotter interface {
+	// snapshot copies the stored data, and returns a function storing the copy back
+	snapshot() (restore func())
+}
+
+func NewMemoryTransactor(rooms TheaterRoomRepository, reservations ReservationRepository, ledger LoyaltyLedgerRepository) *MemoryTransactor {
+	return &MemoryTransactor{
+		rooms:        rooms,
+		reservations: reservations,
+		ledger:       ledger,
+		mutex:        &sync.Mutex{},
+	}
+}
+
+func (t *MemoryTransactor) Transaction(fn func(rooms TheaterRoomRepository, reservations ReservationRepository, ledger LoyaltyLedgerRepository) error) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var restores []func()
+	for _, repository := range []any{t.rooms, t.reservations, t.ledger} {
+		if snapshotter, ok := repository.(snapshotter); ok {
+			restores = append(restores, snapshotter.snapshot())
+		}
+	}
+
+	err := fn(t.rooms, t.reservations, t.ledger)
+	if err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+	return err
+}
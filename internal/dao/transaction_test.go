@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestMemoryTransactorRollsBackFailedTransaction(t *testing.T) {
+	rooms, reservations, ledger := NewTheaterRoomsDAO(), NewReservationDAO(), NewLoyaltyLedgerDAO()
+	transactor := NewMemoryTransactor(rooms, reservations, ledger)
+	earned := types.LoyaltyEntry{CustomerID: 1, ReservationID: 1, Kind: types.LoyaltyEntryEarn, Points: 50, At: time.Now()}
+	err := ledger.AppendLoyaltyEntry(earned)
+	if err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+
+	errFailure := errors.New("failure")
+	err = transactor.Transaction(func(rooms TheaterRoomRepository, reservations ReservationRepository, ledger LoyaltyLedgerRepository) error {
+		err := rooms.HoldSeats(1, []string{"C1"})
+		if err != nil {
+			return err
+		}
+		err = reservations.Update(types.Reservation{ReservationID: 42, PerformanceID: 1, Status: types.ReservationStatusPending})
+		if err != nil {
+			return err
+		}
+		err = ledger.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 1, ReservationID: 42, Kind: types.LoyaltyEntryRedeem, Points: -20, At: time.Now()})
+		if err != nil {
+			return err
+		}
+		return errFailure
+	})
+	if !errors.Is(err, errFailure) {
+		t.Fatalf("Expected transaction failure, got %v", err)
+	}
+
+	if status := seatStatus(t, rooms, 1, "C1"); status != types.SeatStatusFree {
+		t.Errorf("Expected seat C1 to be %s, got %s", types.SeatStatusFree, status)
+	}
+	reservation, err := reservations.Find(42)
+	if err != nil || reservation != nil {
+		t.Errorf("Expected no reservation, got %v (%v)", reservation, err)
+	}
+	entries, err := ledger.FetchLoyaltyEntries(1)
+	if err != nil || len(entries) != 1 || entries[0] != earned {
+		t.Errorf("Expected the earned entry only, got %v (%v)", entries, err)
+	}
+
+	// writes of successful transactions are kept
+	err = transactor.Transaction(func(rooms TheaterRoomRepository, _ ReservationRepository, _ LoyaltyLedgerRepository) error {
+		return rooms.HoldSeats(1, []string{"C1"})
+	})
+	if err != nil {
+		t.Fatalf("Failed to hold seat: %v", err)
+	}
+	if status := seatStatus(t, rooms, 1, "C1"); status != types.SeatStatusBookingPending {
+		t.Errorf("Expected seat C1 to be %s, got %s", types.SeatStatusBookingPending, status)
+	}
+}
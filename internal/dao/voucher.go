@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+type VoucherDAO struct {
+	vouchers map[string]types.Voucher
+	mutex    *sync.RWMutex
+}
+
+func NewVoucherDAO() *VoucherDAO {
+	dao := &VoucherDAO{
+		vouchers: make(map[string]types.Voucher),
+		mutex:    &sync.RWMutex{},
+	}
+
+	dao.vouchers["SPRING23"] = types.Voucher{
+		Code:       "SPRING23",
+		Kind:       types.VoucherKindPercent,
+		Ratio:      big.NewRat(10, 100),
+		ValidFrom:  time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC),
+		ValidUntil: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		MaxUses:    100,
+	}
+	dao.vouchers["CICDFAN"] = types.Voucher{
+		Code:               "CICDFAN",
+		Kind:               types.VoucherKindFixed,
+		Amount:             money.MustParse("10.00", money.EUR),
+		MaxUsesPerCustomer: 1,
+		PerformanceIDs:     []int64{1},
+		Categories:         []types.ZoneCategory{types.ZoneCategoryStandard},
+	}
+
+	return dao
+}
+
+// FetchVoucher simulates a voucher codes repository
+func (dao *VoucherDAO) FetchVoucher(code string) (*types.Voucher, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+
+	voucher, ok := dao.vouchers[code]
+	if !ok {
+		return nil, nil
+	}
+	return &voucher, nil
+}
+
+func (dao *VoucherDAO) SaveVoucher(voucher types.Voucher) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+
+	dao.vouchers[voucher.Code] = voucher
+	return nil
+}
@@ -7,18 +7,18 @@ import (
 
 type VoucherProgramDAO struct{}
 
-func NewVoucherProgramDAO() VoucherProgramDAO {
-	return VoucherProgramDAO{}
+func NewVoucherProgramDAO() *VoucherProgramDAO {
+	return &VoucherProgramDAO{}
 }
 
 // FetchVoucherProgram simulates a voucher program repository
-func (dao *VoucherProgramDAO) FetchVoucherProgram(reservationDate time.Time) *big.Float {
-	voucher := big.NewFloat(0)
+func (dao *VoucherProgramDAO) FetchVoucherProgram(reservationDate time.Time) (*big.Rat, error) {
+	voucher := new(big.Rat)
 
 	// applies from reservation date, not performance date
 	if reservationDate.Before(time.Date(2023, time.April, 30, 0, 0, 0, 0, time.UTC)) {
-		voucher.SetFloat64(0.20)
+		voucher.SetFrac64(20, 100)
 	}
 
-	return voucher
+	return voucher, nil
 }
@@ -0,0 +1,34 @@
+package encoder
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// WriteDoorCheckCSV writes the concession seats of a performance as a CSV document with a header line,
+// for the staff checking the proofs of eligibility at the door
+func WriteDoorCheckCSV(w io.Writer, entries []types.DoorCheckEntry) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"reservation", "customer", "seat", "category", "ticket type", "eligibility"})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err = writer.Write([]string{
+			strconv.FormatInt(entry.ReservationID, 10),
+			strconv.FormatInt(entry.CustomerID, 10),
+			entry.SeatID,
+			string(entry.Category),
+			string(entry.TicketType),
+			string(entry.Eligibility),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
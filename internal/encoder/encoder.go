@@ -0,0 +1,148 @@
+package encoder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+const (
+	FormatXML  = "xml"
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// Encoder writes a reservation result in a given output format.
+type Encoder interface {
+	Encode(w io.Writer, result types.ReservationResult) error
+}
+
+var (
+	registry = map[string]Encoder{
+		FormatXML:  XMLEncoder{},
+		FormatJSON: JSONEncoder{},
+		FormatText: TextEncoder{},
+	}
+	registryMutex sync.RWMutex
+)
+
+// Register makes an encoder available under the given format name, replacing any previous one.
+func Register(format string, encoder Encoder) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry[format] = encoder
+}
+
+// Lookup returns the encoder registered for the given format name.
+func Lookup(format string) (Encoder, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	encoder, ok := registry[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return encoder, nil
+}
+
+// Formats lists the registered format names, sorted.
+func Formats() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	formats := make([]string, 0, len(registry))
+	for format := range registry {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+	return formats
+}
+
+// Encode writes result to w in the given format.
+func Encode(w io.Writer, format string, result types.ReservationResult) error {
+	encoder, err := Lookup(format)
+	if err != nil {
+		return err
+	}
+	return encoder.Encode(w, result)
+}
+
+// EncodeToString returns result encoded in the given format.
+func EncodeToString(format string, result types.ReservationResult) (string, error) {
+	var sb strings.Builder
+	err := Encode(&sb, format, result)
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// formatAmount writes the amount in a locale-independent way, for machine-readable outputs
+func formatAmount(amount money.Money) string {
+	return amount.Decimal()
+}
+
+// resultLocale returns the locale amounts of the result are written in
+func resultLocale(result types.ReservationResult) (money.Locale, error) {
+	return money.LookupLocale(result.Request.Locale)
+}
+
+// seatTicketType returns the ticket type of a seat of the result, adult when the request does not set ticket types
+func seatTicketType(result types.ReservationResult, seat string) types.TicketType {
+	ticketType, ok := result.SeatTicketTypes[seat]
+	if !ok {
+		return types.TicketTypeAdult
+	}
+	return ticketType
+}
+
+// seatEligibility returns the eligibility of a seat of the result, not required for seats which are not concessions
+func seatEligibility(result types.ReservationResult, seat string) types.Eligibility {
+	eligibility, ok := result.SeatEligibility[seat]
+	if !ok {
+		return types.EligibilityNotRequired
+	}
+	return eligibility
+}
+
+var eligibilityLabels = map[types.Eligibility]string{
+	types.EligibilityVerified:    "proof verified",
+	types.EligibilityCheckAtDoor: "proof to show at the door",
+}
+
+var priceLineLabels = map[types.PriceLineKind]string{
+	types.PriceLineBasePrice:          "Base price",
+	types.PriceLineCategorySurcharge:  "Category surcharge",
+	types.PriceLineDemandAdjustment:   "Demand tier",
+	types.PriceLineTicketType:         "Ticket",
+	types.PriceLineGroupDiscount:      "Group discount",
+	types.PriceLineSubscriberDiscount: "Subscriber discount",
+	types.PriceLineVoucherDiscount:    "Voucher discount",
+	types.PriceLineVoucherCode:        "Voucher code",
+	types.PriceLinePointsRedemption:   "Loyalty points",
+	types.PriceLineRounding:           "Rounding",
+}
+
+// priceLineLabel returns a human-readable description of a price line
+func priceLineLabel(line types.PriceLine) string {
+	label, ok := priceLineLabels[line.Kind]
+	if !ok {
+		label = string(line.Kind)
+	}
+	if line.Rule != "" {
+		label += " " + line.Rule
+	}
+	if line.SeatID != "" {
+		label += " " + line.SeatID
+	}
+	return label
+}
@@ -0,0 +1,374 @@
+package encoder
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+var result = types.ReservationResult{
+	Request: types.ReservationRequest{
+		CustomerID:       1,
+		ReservationCount: 2,
+		Category:         types.ZoneCategoryStandard,
+		Performance: types.Performance{
+			ID:        4,
+			Play:      "Tom & Jerry <live>",
+			StartTime: time.Date(2023, time.May, 2, 20, 30, 0, 0, time.UTC),
+		},
+	},
+	Reservation:    types.Reservation{ReservationID: 42},
+	Status:         types.ReservationStatusFulfillable,
+	Seats:          []string{"C4", "C5"},
+	SeatCategories: map[string]types.ZoneCategory{"C4": types.ZoneCategoryStandard, "C5": types.ZoneCategoryStandard},
+	Price: types.PriceBreakdown{
+		Lines: []types.PriceLine{
+			{Kind: types.PriceLineBasePrice, SeatID: "C4", Amount: money.New(2800, money.EUR)},
+			{Kind: types.PriceLineBasePrice, SeatID: "C5", Amount: money.New(2800, money.EUR)},
+		},
+		TotalAmountDue: money.New(5600, money.EUR),
+	},
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: FormatXML,
+			expected: `<reservation>
+	<performance>
+		<play>Tom &amp; Jerry &lt;live&gt;</play>
+		<date>2023-05-02</date>
+		<time>20:30:00</time>
+	</performance>
+	<reservationId>42</reservationId>
+	<reservationStatus>FULFILLABLE</reservationStatus>
+	<seats>
+		<seat>
+			<id>C4</id>
+			<category>STANDARD</category>
+		</seat>
+		<seat>
+			<id>C5</id>
+			<category>STANDARD</category>
+		</seat>
+	</seats>
+	<seatCategory>STANDARD</seatCategory>
+	<totalAmountDue>56.00€</totalAmountDue>
+</reservation>
+`,
+		},
+		{
+			format: FormatJSON,
+			expected: `{
+  "reservationId": 42,
+  "performance": {
+    "id": 4,
+    "play": "Tom & Jerry <live>",
+    "date": "2023-05-02",
+    "time": "20:30:00"
+  },
+  "status": "FULFILLABLE",
+  "seats": [
+    {
+      "id": "C4",
+      "category": "STANDARD",
+      "ticketType": "ADULT",
+      "eligibility": "NOT_REQUIRED"
+    },
+    {
+      "id": "C5",
+      "category": "STANDARD",
+      "ticketType": "ADULT",
+      "eligibility": "NOT_REQUIRED"
+    }
+  ],
+  "seatCategory": "STANDARD",
+  "contiguous": true,
+  "splitOffer": [],
+  "seatRejections": [],
+  "priceLines": [
+    {
+      "kind": "BASE_PRICE",
+      "seatId": "C4",
+      "amount": "28.00"
+    },
+    {
+      "kind": "BASE_PRICE",
+      "seatId": "C5",
+      "amount": "28.00"
+    }
+  ],
+  "discounts": [],
+  "totalAmountDue": {
+    "amount": "56.00",
+    "currency": "EUR",
+    "formatted": "56.00€"
+  }
+}
+`,
+		},
+		{
+			format: FormatText,
+			expected: `Reservation #42
+Tom & Jerry <live>
+Tuesday, May 2 2023 at 20:30
+
+2 seat(s) in STANDARD category:
+  - C4   STANDARD
+  - C5   STANDARD
+
+  Base price C4                     28.00€
+  Base price C5                     28.00€
+Total amount due: 56.00€
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			actual, err := EncodeToString(test.format, result)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if actual != test.expected {
+				t.Errorf("Unexpected %s output, expected:\n%s\ngot:\n%s", test.format, test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeUnknownFormat(t *testing.T) {
+	_, err := EncodeToString("yaml", result)
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestEncodeWithLocale(t *testing.T) {
+	localized := result
+	localized.Request.Locale = "fr-FR"
+	localized.Price.Lines = []types.PriceLine{
+		{Kind: types.PriceLineBasePrice, SeatID: "C4", Amount: money.New(123456, money.EUR)},
+		{Kind: types.PriceLineSubscriberDiscount, Amount: money.New(-5, money.EUR)},
+	}
+	localized.Price.TotalAmountDue = money.New(123451, money.EUR)
+
+	// French writes a narrow no-break space between groups, and a no-break space before the symbol
+	tests := map[string]string{
+		FormatXML:  "<totalAmountDue>1\u202f234,51\u00a0€</totalAmountDue>",
+		FormatJSON: "\"formatted\": \"1\u202f234,51\u00a0€\"",
+		FormatText: "  Base price C4                 1\u202f234,56\u00a0€\n  Subscriber discount              -0,05\u00a0€\nTotal amount due: 1\u202f234,51\u00a0€\n",
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			actual, err := EncodeToString(format, localized)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if !strings.Contains(actual, expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", format, expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeUnknownLocale(t *testing.T) {
+	unknown := result
+	unknown.Request.Locale = "tlh"
+	_, err := EncodeToString(FormatXML, unknown)
+	if !errors.Is(err, money.ErrUnknownLocale) {
+		t.Errorf("Expected ErrUnknownLocale, got %v", err)
+	}
+}
+
+func TestEncodeDiscountOutcomes(t *testing.T) {
+	discounted := result
+	discounted.Price.DiscountOutcomes = []types.DiscountOutcome{
+		{
+			Discount: types.Discount{Kind: types.PriceLineVoucherDiscount, Ratio: big.NewRat(1, 5), Priority: 10, Stacking: types.StackingBestOnly},
+			Status:   types.DiscountStatusSuppressed,
+			Reason:   "best of SUBSCRIBER_DISCOUNT GOLD",
+		},
+	}
+
+	tests := map[string]string{
+		FormatJSON: `"discounts": [
+    {
+      "kind": "VOUCHER_DISCOUNT",
+      "ratio": "1/5",
+      "priority": 10,
+      "stacking": "BEST_ONLY",
+      "status": "SUPPRESSED",
+      "reason": "best of SUBSCRIBER_DISCOUNT GOLD"
+    }
+  ]`,
+		FormatText: "  Voucher discount suppressed: best of SUBSCRIBER_DISCOUNT GOLD\nTotal amount due: 56.00€\n",
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			actual, err := EncodeToString(format, discounted)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if !strings.Contains(actual, expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", format, expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeTicketTypes(t *testing.T) {
+	family := result
+	family.SeatTicketTypes = map[string]types.TicketType{"C4": types.TicketTypeAdult, "C5": types.TicketTypeChild}
+	family.Price.Lines = []types.PriceLine{
+		{Kind: types.PriceLineBasePrice, SeatID: "C5", Amount: money.New(2800, money.EUR)},
+		{Kind: types.PriceLineTicketType, SeatID: "C5", Rule: "CHILD", Amount: money.New(-1400, money.EUR)},
+	}
+
+	tests := map[string]string{
+		FormatXML: "\t\t\t<id>C5</id>\n\t\t\t<category>STANDARD</category>\n\t\t\t<ticketType>CHILD</ticketType>\n",
+		FormatJSON: `"id": "C5",
+      "category": "STANDARD",
+      "ticketType": "CHILD",
+      "eligibility": "NOT_REQUIRED"`,
+		FormatText: "  - C4   STANDARD ADULT\n  - C5   STANDARD CHILD\n\n  Base price C5                     28.00€\n  Ticket CHILD C5                  -14.00€\n",
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			actual, err := EncodeToString(format, family)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if !strings.Contains(actual, expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", format, expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeEligibility(t *testing.T) {
+	concession := result
+	concession.SeatTicketTypes = map[string]types.TicketType{"C4": types.TicketTypeAdult, "C5": types.TicketTypeStudent}
+	concession.SeatEligibility = map[string]types.Eligibility{"C5": types.EligibilityCheckAtDoor}
+
+	tests := map[string]string{
+		FormatXML: "\t\t\t<ticketType>STUDENT</ticketType>\n\t\t\t<eligibility>CHECK_AT_DOOR</eligibility>\n",
+		FormatJSON: `"ticketType": "STUDENT",
+      "eligibility": "CHECK_AT_DOOR"`,
+		FormatText: "  - C4   STANDARD ADULT\n  - C5   STANDARD STUDENT (proof to show at the door)\n",
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			actual, err := EncodeToString(format, concession)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if !strings.Contains(actual, expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", format, expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeSplitSeating(t *testing.T) {
+	split := result
+	split.Reservation.SplitSeats = true
+
+	tests := map[string]string{
+		FormatXML:  "\t<splitSeats>true</splitSeats>\n",
+		FormatJSON: `"contiguous": false,`,
+		FormatText: "  - C5   STANDARD\nSeats are not all side by side\n",
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			actual, err := EncodeToString(format, split)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if !strings.Contains(actual, expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", format, expected, actual)
+			}
+		})
+	}
+
+	offer := result
+	offer.Status = types.ReservationStatusAborted
+	offer.Seats = nil
+	offer.SplitOffer = []string{"C4", "D4"}
+
+	tests = map[string]string{
+		FormatXML:  "\t<splitOffer>\n\t\t<seat>\n\t\t\t<id>C4</id>\n\t\t\t<category>STANDARD</category>\n\t\t</seat>\n",
+		FormatJSON: "\"splitOffer\": [\n    \"C4\",\n    \"D4\"\n  ],",
+		FormatText: "Seats not side by side offered: C4, D4\n",
+	}
+	for format, expected := range tests {
+		t.Run(format+" offer", func(t *testing.T) {
+			actual, err := EncodeToString(format, offer)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if !strings.Contains(actual, expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", format, expected, actual)
+			}
+		})
+	}
+}
+
+func TestEncodeSeatRejections(t *testing.T) {
+	rejected := result
+	rejected.Status = types.ReservationStatusAborted
+	rejected.Seats = nil
+	rejected.Request.SeatIDs = []string{"C4", "C5", "Z9"}
+	rejected.SeatRejections = map[string]types.SeatRejection{"Z9": types.SeatRejectionUnknown, "C4": types.SeatRejectionBooked}
+
+	tests := map[string]string{
+		FormatXML: "\t<seatRejections>\n\t\t<seat>\n\t\t\t<id>C4</id>\n\t\t\t<reason>BOOKED</reason>\n\t\t</seat>\n\t\t<seat>\n\t\t\t<id>Z9</id>\n\t\t\t<reason>UNKNOWN</reason>\n",
+		FormatJSON: `"seatRejections": [
+    {
+      "seatId": "C4",
+      "reason": "BOOKED"
+    },
+    {
+      "seatId": "Z9",
+      "reason": "UNKNOWN"
+    }
+  ],`,
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			actual, err := EncodeToString(format, rejected)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if !strings.Contains(actual, expected) {
+				t.Errorf("Expected %s output to contain %q, got:\n%s", format, expected, actual)
+			}
+		})
+	}
+}
+
+func TestWriteDoorCheckCSV(t *testing.T) {
+	var sb strings.Builder
+	err := WriteDoorCheckCSV(&sb, []types.DoorCheckEntry{
+		{ReservationID: 42, CustomerID: 3, SeatID: "C5", Category: types.ZoneCategoryStandard, TicketType: types.TicketTypeStudent, Eligibility: types.EligibilityVerified},
+		{ReservationID: 43, CustomerID: 4, SeatID: "A1", Category: types.ZoneCategoryPremium, TicketType: types.TicketTypeSenior, Eligibility: types.EligibilityCheckAtDoor},
+	})
+	if err != nil {
+		t.Fatalf("Failed to write door check: %v", err)
+	}
+	expected := `reservation,customer,seat,category,ticket type,eligibility
+42,3,C5,STANDARD,STUDENT,VERIFIED
+43,4,A1,PREMIUM,SENIOR,CHECK_AT_DOOR
+`
+	if sb.String() != expected {
+		t.Errorf("Unexpected door check, expected:\n%s\ngot:\n%s", expected, sb.String())
+	}
+}
@@ -0,0 +1,147 @@
+package encoder
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// JSONEncoder writes the reservation as a JSON document.
+//
+// The schema is stable: every field is always present, except "error" which is only set
+// for aborted reservations.
+type JSONEncoder struct{}
+
+type jsonReservation struct {
+	ReservationID int64           `json:"reservationId"`
+	Performance   jsonPerformance `json:"performance"`
+	Status        string          `json:"status"`
+	Seats         []jsonSeat      `json:"seats"`
+	SeatCategory  string          `json:"seatCategory"`
+	// Contiguous tells whether the seats are all side by side in a same row
+	Contiguous bool `json:"contiguous"`
+	// SplitOffer are the seats offered when the request rejects split seating, they are not held
+	SplitOffer []string `json:"splitOffer"`
+	// SeatRejections tell why the seats chosen by the customer cannot be reserved, in the order they were chosen
+	SeatRejections []jsonSeatRejection `json:"seatRejections"`
+	PriceLines     []jsonPriceLine     `json:"priceLines"`
+	Discounts      []jsonDiscount      `json:"discounts"`
+	TotalAmountDue jsonAmount          `json:"totalAmountDue"`
+	Error          string              `json:"error,omitempty"`
+}
+
+type jsonPerformance struct {
+	ID   int64  `json:"id"`
+	Play string `json:"play"`
+	Date string `json:"date"`
+	Time string `json:"time"`
+}
+
+type jsonSeat struct {
+	ID         string `json:"id"`
+	Category   string `json:"category"`
+	TicketType string `json:"ticketType"`
+	// Eligibility tells whether a proof of eligibility to the concession must be shown at the door
+	Eligibility string `json:"eligibility"`
+}
+
+type jsonSeatRejection struct {
+	SeatID string `json:"seatId"`
+	Reason string `json:"reason"`
+}
+
+type jsonPriceLine struct {
+	Kind   string `json:"kind"`
+	SeatID string `json:"seatId,omitempty"`
+	Rule   string `json:"rule,omitempty"`
+	Amount string `json:"amount"`
+}
+
+type jsonDiscount struct {
+	Kind string `json:"kind"`
+	Rule string `json:"rule,omitempty"`
+	// Ratio is the exact ratio of the discount, as a fraction such as "7/40"
+	Ratio    string `json:"ratio"`
+	Priority int    `json:"priority"`
+	Stacking string `json:"stacking"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type jsonAmount struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	// Formatted is the amount written for the locale of the customer
+	Formatted string `json:"formatted"`
+}
+
+func (JSONEncoder) Encode(w io.Writer, result types.ReservationResult) error {
+	locale, err := resultLocale(result)
+	if err != nil {
+		return err
+	}
+	performance := result.Request.Performance
+	doc := jsonReservation{
+		ReservationID: result.Reservation.ReservationID,
+		Performance: jsonPerformance{
+			ID:   performance.ID,
+			Play: performance.Play,
+			Date: performance.StartTime.Format("2006-01-02"),
+			Time: performance.StartTime.Format("15:04:05"),
+		},
+		Status:         string(result.Status),
+		Seats:          make([]jsonSeat, 0, len(result.Seats)),
+		SeatCategory:   string(result.Request.Category),
+		Contiguous:     result.Contiguous(),
+		SplitOffer:     append([]string{}, result.SplitOffer...),
+		SeatRejections: make([]jsonSeatRejection, 0, len(result.SeatRejections)),
+		PriceLines:     make([]jsonPriceLine, 0, len(result.Price.Lines)),
+		Discounts:      make([]jsonDiscount, 0, len(result.Price.DiscountOutcomes)),
+		TotalAmountDue: jsonAmount{
+			Amount:    formatAmount(result.Price.TotalAmountDue),
+			Currency:  string(result.Price.TotalAmountDue.Currency),
+			Formatted: locale.Format(result.Price.TotalAmountDue),
+		},
+	}
+	for _, seat := range result.Seats {
+		doc.Seats = append(doc.Seats, jsonSeat{
+			ID:          seat,
+			Category:    string(result.SeatCategories[seat]),
+			TicketType:  string(seatTicketType(result, seat)),
+			Eligibility: string(seatEligibility(result, seat)),
+		})
+	}
+	for _, seat := range result.Request.SeatIDs {
+		if rejection, ok := result.SeatRejections[seat]; ok {
+			doc.SeatRejections = append(doc.SeatRejections, jsonSeatRejection{SeatID: seat, Reason: string(rejection)})
+		}
+	}
+	for _, line := range result.Price.Lines {
+		doc.PriceLines = append(doc.PriceLines, jsonPriceLine{
+			Kind:   string(line.Kind),
+			SeatID: line.SeatID,
+			Rule:   line.Rule,
+			Amount: formatAmount(line.Amount),
+		})
+	}
+	for _, outcome := range result.Price.DiscountOutcomes {
+		doc.Discounts = append(doc.Discounts, jsonDiscount{
+			Kind:     string(outcome.Discount.Kind),
+			Rule:     outcome.Discount.Rule,
+			Ratio:    outcome.Discount.Ratio.RatString(),
+			Priority: outcome.Discount.Priority,
+			Stacking: string(outcome.Discount.Stacking),
+			Status:   string(outcome.Status),
+			Reason:   outcome.Reason,
+		})
+	}
+	if result.Err != nil {
+		doc.Error = result.Err.Error()
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
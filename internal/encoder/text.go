@@ -0,0 +1,65 @@
+package encoder
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// TextEncoder writes a human-readable receipt.
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(w io.Writer, result types.ReservationResult) error {
+	locale, err := resultLocale(result)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	performance := result.Request.Performance
+
+	fmt.Fprintf(&sb, "Reservation #%d\n", result.Reservation.ReservationID)
+	fmt.Fprintf(&sb, "%s\n", performance.Play)
+	fmt.Fprintf(&sb, "%s at %s\n", performance.StartTime.Format("Monday, January 2 2006"), performance.StartTime.Format("15:04"))
+	sb.WriteString("\n")
+
+	if result.Status == types.ReservationStatusFulfillable {
+		fmt.Fprintf(&sb, "%d seat(s) in %s category:\n", len(result.Seats), result.Request.Category)
+		for _, seat := range result.Seats {
+			if eligibility, ok := result.SeatEligibility[seat]; ok {
+				fmt.Fprintf(&sb, "  - %-4s %-8s %s (%s)\n", seat, result.SeatCategories[seat], result.SeatTicketTypes[seat], eligibilityLabels[eligibility])
+			} else if ticketType, ok := result.SeatTicketTypes[seat]; ok {
+				fmt.Fprintf(&sb, "  - %-4s %-8s %s\n", seat, result.SeatCategories[seat], ticketType)
+			} else {
+				fmt.Fprintf(&sb, "  - %-4s %s\n", seat, result.SeatCategories[seat])
+			}
+		}
+		if !result.Contiguous() {
+			sb.WriteString("Seats are not all side by side\n")
+		}
+	} else {
+		sb.WriteString("Reservation aborted")
+		if result.Err != nil {
+			fmt.Fprintf(&sb, ": %v", result.Err)
+		}
+		sb.WriteString("\n")
+		if len(result.SplitOffer) > 0 {
+			fmt.Fprintf(&sb, "Seats not side by side offered: %s\n", strings.Join(result.SplitOffer, ", "))
+		}
+	}
+	sb.WriteString("\n")
+	for _, line := range result.Price.Lines {
+		fmt.Fprintf(&sb, "  %-28s %11s\n", priceLineLabel(line), locale.Format(line.Amount))
+	}
+	for _, outcome := range result.Price.DiscountOutcomes {
+		if outcome.Status != types.DiscountStatusApplied {
+			label := priceLineLabel(types.PriceLine{Kind: outcome.Discount.Kind, Rule: outcome.Discount.Rule})
+			fmt.Fprintf(&sb, "  %s %s: %s\n", label, strings.ToLower(string(outcome.Status)), outcome.Reason)
+		}
+	}
+	fmt.Fprintf(&sb, "Total amount due: %s\n", locale.Format(result.Price.TotalAmountDue))
+
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,108 @@
+package encoder
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// XMLEncoder writes the historical <reservation> document.
+type XMLEncoder struct{}
+
+type xmlReservation struct {
+	XMLName           xml.Name       `xml:"reservation"`
+	Performance       xmlPerformance `xml:"performance"`
+	ReservationID     int64          `xml:"reservationId"`
+	ReservationStatus string         `xml:"reservationStatus"`
+	Seats             *xmlSeats      `xml:"seats"`
+	SeatCategory      string         `xml:"seatCategory"`
+	// SplitSeats and SplitOffer are only written when the request allows split seating, to keep the historical document
+	SplitSeats bool      `xml:"splitSeats,omitempty"`
+	SplitOffer *xmlSeats `xml:"splitOffer"`
+	// SeatRejections are only written when some seats chosen by the customer cannot be reserved
+	SeatRejections *xmlSeatRejections `xml:"seatRejections"`
+	TotalAmountDue string             `xml:"totalAmountDue"`
+}
+
+type xmlPerformance struct {
+	Play string `xml:"play"`
+	Date string `xml:"date"`
+	Time string `xml:"time"`
+}
+
+type xmlSeats struct {
+	Seats []xmlSeat `xml:"seat"`
+}
+
+type xmlSeatRejections struct {
+	Seats []xmlSeatRejection `xml:"seat"`
+}
+
+type xmlSeatRejection struct {
+	ID     string `xml:"id"`
+	Reason string `xml:"reason"`
+}
+
+type xmlSeat struct {
+	ID       string `xml:"id"`
+	Category string `xml:"category"`
+	// TicketType is only written when the request sets ticket types, to keep the historical document
+	TicketType string `xml:"ticketType,omitempty"`
+	// Eligibility is only written for concession seats
+	Eligibility string `xml:"eligibility,omitempty"`
+}
+
+func (XMLEncoder) Encode(w io.Writer, result types.ReservationResult) error {
+	locale, err := resultLocale(result)
+	if err != nil {
+		return err
+	}
+	performance := result.Request.Performance
+	doc := xmlReservation{
+		Performance: xmlPerformance{
+			Play: performance.Play,
+			Date: performance.StartTime.Format("2006-01-02"),
+			Time: performance.StartTime.Format("15:04:05"),
+		},
+		ReservationID:     result.Reservation.ReservationID,
+		ReservationStatus: string(result.Status),
+		SeatCategory:      string(result.Request.Category),
+		TotalAmountDue:    locale.Format(result.Price.TotalAmountDue),
+		SplitSeats:        !result.Contiguous(),
+	}
+	if len(result.Seats) > 0 {
+		doc.Seats = &xmlSeats{}
+		for _, seat := range result.Seats {
+			doc.Seats.Seats = append(doc.Seats.Seats, xmlSeat{
+				ID:          seat,
+				Category:    string(result.SeatCategories[seat]),
+				TicketType:  string(result.SeatTicketTypes[seat]),
+				Eligibility: string(result.SeatEligibility[seat]),
+			})
+		}
+	}
+	if len(result.SplitOffer) > 0 {
+		doc.SplitOffer = &xmlSeats{}
+		for _, seat := range result.SplitOffer {
+			doc.SplitOffer.Seats = append(doc.SplitOffer.Seats, xmlSeat{ID: seat, Category: string(result.Request.Category)})
+		}
+	}
+	if len(result.SeatRejections) > 0 {
+		doc.SeatRejections = &xmlSeatRejections{}
+		for _, seat := range result.Request.SeatIDs {
+			if rejection, ok := result.SeatRejections[seat]; ok {
+				doc.SeatRejections.Seats = append(doc.SeatRejections.Seats, xmlSeatRejection{ID: seat, Reason: string(rejection)})
+			}
+		}
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
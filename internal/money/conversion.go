@@ -0,0 +1,85 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+)
+
+var ErrNoConversionRate = errors.New("no conversion rate")
+
+// ConversionTable holds fixed exchange rates, all relative to a base currency
+type ConversionTable struct {
+	rates map[Currency]*big.Rat
+}
+
+// conversionTableFile is the layout of a conversion table file, e.g.
+//
+//	{"base": "EUR", "rates": {"GBP": "0.86", "USD": "1.08"}}
+//
+// where each rate is the amount of the currency worth one unit of the base currency
+type conversionTableFile struct {
+	Base  Currency            `json:"base"`
+	Rates map[Currency]string `json:"rates"`
+}
+
+// LoadConversionTable reads a conversion table from a local JSON file
+func LoadConversionTable(path string) (*ConversionTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open conversion table: %w", err)
+	}
+	defer f.Close()
+
+	table, err := ParseConversionTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
+
+// ParseConversionTable reads a conversion table in the JSON layout of the conversion table files
+func ParseConversionTable(r io.Reader) (*ConversionTable, error) {
+	var file conversionTableFile
+	err := json.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("decode conversion table: %w", err)
+	}
+	if file.Base == "" {
+		return nil, errors.New("conversion table has no base currency")
+	}
+
+	table := &ConversionTable{
+		rates: map[Currency]*big.Rat{file.Base: big.NewRat(1, 1)},
+	}
+	for currency, rate := range file.Rates {
+		r, ok := new(big.Rat).SetString(rate)
+		if !ok || r.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid conversion rate %q for %s", rate, currency)
+		}
+		table.rates[currency] = r
+	}
+	return table, nil
+}
+
+// Convert converts the amount into the given currency, rounded to its minor unit with the given mode
+func (t *ConversionTable) Convert(m Money, to Currency, mode RoundingMode) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	fromRate, ok := t.rates[m.Currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w from %s", ErrNoConversionRate, m.Currency)
+	}
+	toRate, ok := t.rates[to]
+	if !ok {
+		return Money{}, fmt.Errorf("%w to %s", ErrNoConversionRate, to)
+	}
+
+	converted := new(big.Rat).Mul(m.Rat(), toRate)
+	converted.Quo(converted, fromRate)
+	return FromRat(converted, to, mode), nil
+}
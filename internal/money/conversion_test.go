@@ -0,0 +1,56 @@
+package money
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	table, err := LoadConversionTable("testdata/rates.json")
+	if err != nil {
+		t.Fatalf("Failed to load conversion table: %v", err)
+	}
+
+	tests := []struct {
+		amount   Money
+		to       Currency
+		expected Money
+	}{
+		{New(3500, EUR), EUR, New(3500, EUR)},
+		{New(3500, EUR), USD, New(3780, USD)},
+		{New(3500, EUR), JPY, New(5600, JPY)},
+		// 32 GBP = 37.2093… EUR
+		{New(3200, GBP), EUR, New(3721, EUR)},
+		// 32 GBP = 40.1860… USD, converted through the base currency without intermediate rounding
+		{New(3200, GBP), USD, New(4019, USD)},
+	}
+	for _, test := range tests {
+		actual, err := table.Convert(test.amount, test.to, HalfUp)
+		if err != nil {
+			t.Fatalf("Failed to convert %v to %s: %v", test.amount, test.to, err)
+		}
+		if actual != test.expected {
+			t.Errorf("Expected %v to be converted to %v, got %v", test.amount, test.expected, actual)
+		}
+	}
+
+	_, err = table.Convert(New(100, EUR), CHF, HalfUp)
+	if !errors.Is(err, ErrNoConversionRate) {
+		t.Errorf("Expected ErrNoConversionRate, got %v", err)
+	}
+}
+
+func TestParseInvalidConversionTable(t *testing.T) {
+	tests := map[string]string{
+		"no base":       `{"rates": {"USD": "1.08"}}`,
+		"invalid rate":  `{"base": "EUR", "rates": {"USD": "one"}}`,
+		"negative rate": `{"base": "EUR", "rates": {"USD": "-1.08"}}`,
+		"not JSON":      `base: EUR`,
+	}
+	for name, input := range tests {
+		if _, err := ParseConversionTable(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
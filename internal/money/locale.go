@@ -0,0 +1,106 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownLocale = errors.New("unknown locale")
+
+// currencySymbols are the symbols of the currencies, amounts of other currencies are written with their code
+var currencySymbols = map[Currency]string{
+	EUR: "€",
+	USD: "$",
+	GBP: "£",
+	JPY: "¥",
+}
+
+// Symbol returns the symbol of the currency, or its ISO code when it has no symbol of its own
+func (c Currency) Symbol() string {
+	symbol, ok := currencySymbols[c]
+	if !ok {
+		return string(c)
+	}
+	return symbol
+}
+
+// Locale tells how amounts are written for the customers of a region
+type Locale struct {
+	Tag string
+	// DecimalSeparator separates the major units from the minor ones
+	DecimalSeparator string
+	// GroupSeparator separates the thousands, there is no grouping when it is empty
+	GroupSeparator string
+	// SymbolFirst puts the currency symbol before the amount instead of after
+	SymbolFirst bool
+	// SymbolSeparator is written between the currency symbol and the amount
+	SymbolSeparator string
+}
+
+// DefaultLocale is the historical format of the reservations, e.g. "1234.50€"
+var DefaultLocale = Locale{
+	DecimalSeparator: ".",
+}
+
+// locales are the supported locales, by tag: French and German separate the amount and the symbol with a no-break space,
+// and French groups thousands with a narrow no-break space
+var locales = map[string]Locale{
+	"en-US": {Tag: "en-US", DecimalSeparator: ".", GroupSeparator: ",", SymbolFirst: true},
+	"en-GB": {Tag: "en-GB", DecimalSeparator: ".", GroupSeparator: ",", SymbolFirst: true},
+	"fr-FR": {Tag: "fr-FR", DecimalSeparator: ",", GroupSeparator: "\u202f", SymbolSeparator: "\u00a0"},
+	"de-DE": {Tag: "de-DE", DecimalSeparator: ",", GroupSeparator: ".", SymbolSeparator: "\u00a0"},
+	"de-CH": {Tag: "de-CH", DecimalSeparator: ".", GroupSeparator: "’", SymbolFirst: true, SymbolSeparator: "\u00a0"},
+}
+
+// LookupLocale returns the locale of the given BCP 47 tag, such as "fr-FR", or DefaultLocale when the tag is empty
+func LookupLocale(tag string) (Locale, error) {
+	if tag == "" {
+		return DefaultLocale, nil
+	}
+	locale, ok := locales[tag]
+	if !ok {
+		return Locale{}, fmt.Errorf("%w: %q", ErrUnknownLocale, tag)
+	}
+	return locale, nil
+}
+
+// FormatNumber writes the amount without its currency, e.g. "1,234.50" for en-US
+func (l Locale) FormatNumber(m Money) string {
+	decimal := m.Decimal()
+	sign := ""
+	if strings.HasPrefix(decimal, "-") {
+		sign, decimal = "-", decimal[1:]
+	}
+
+	integer, fraction, hasFraction := strings.Cut(decimal, ".")
+	if l.GroupSeparator != "" {
+		var groups []string
+		for len(integer) > 3 {
+			groups = append([]string{integer[len(integer)-3:]}, groups...)
+			integer = integer[:len(integer)-3]
+		}
+		integer = strings.Join(append([]string{integer}, groups...), l.GroupSeparator)
+	}
+
+	if !hasFraction {
+		return sign + integer
+	}
+	return sign + integer + l.DecimalSeparator + fraction
+}
+
+// Format writes the amount with its currency symbol, e.g. "$1,234.50" for en-US or "1 234,50 €" for fr-FR (with no-break spaces)
+func (l Locale) Format(m Money) string {
+	number := l.FormatNumber(m)
+	symbol := m.Currency.Symbol()
+	if symbol == "" {
+		return number
+	}
+	if !l.SymbolFirst {
+		return number + l.SymbolSeparator + symbol
+	}
+	if strings.HasPrefix(number, "-") {
+		return "-" + symbol + l.SymbolSeparator + number[1:]
+	}
+	return symbol + l.SymbolSeparator + number
+}
@@ -0,0 +1,41 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLocaleFormat(t *testing.T) {
+	tests := []struct {
+		locale   string
+		amount   Money
+		expected string
+	}{
+		{"", New(9240, EUR), "92.40€"},
+		{"", New(123456789, EUR), "1234567.89€"},
+		{"en-US", New(123456789, USD), "$1,234,567.89"},
+		{"en-US", New(-1531, USD), "-$15.31"},
+		{"en-GB", New(3200, GBP), "£32.00"},
+		{"fr-FR", New(123456, EUR), "1\u202f234,56\u00a0€"},
+		{"fr-FR", New(-5, EUR), "-0,05\u00a0€"},
+		{"de-DE", New(123456, EUR), "1.234,56\u00a0€"},
+		{"de-CH", New(123456, CHF), "CHF\u00a01’234.56"},
+		{"en-US", New(150000, JPY), "¥150,000"},
+	}
+	for _, test := range tests {
+		locale, err := LookupLocale(test.locale)
+		if err != nil {
+			t.Fatalf("Failed to look up locale %q: %v", test.locale, err)
+		}
+		if actual := locale.Format(test.amount); actual != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.locale, test.expected, actual)
+		}
+	}
+}
+
+func TestLookupUnknownLocale(t *testing.T) {
+	_, err := LookupLocale("xx-XX")
+	if !errors.Is(err, ErrUnknownLocale) {
+		t.Errorf("Expected ErrUnknownLocale, got %v", err)
+	}
+}
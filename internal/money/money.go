@@ -0,0 +1,157 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code
+type Currency string
+
+const (
+	EUR Currency = "EUR"
+	USD Currency = "USD"
+	GBP Currency = "GBP"
+	CHF Currency = "CHF"
+	JPY Currency = "JPY"
+)
+
+// minorUnitDigits lists the currencies whose minor unit is not the cent
+var minorUnitDigits = map[Currency]int{
+	JPY: 0,
+}
+
+// MinorUnitDigits is the number of decimal digits of the minor unit of the currency, e.g. 2 for cents
+func (c Currency) MinorUnitDigits() int {
+	digits, ok := minorUnitDigits[c]
+	if !ok {
+		return 2
+	}
+	return digits
+}
+
+// minorUnitsPerMajor returns 10^digits, e.g. 100 cents per euro
+func (c Currency) minorUnitsPerMajor() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.MinorUnitDigits())), nil)
+}
+
+// Money is an exact amount of money, stored as an integer number of minor units (e.g. cents) of its currency.
+//
+// The zero Money has no currency, it can be added to or compared with an amount of any currency.
+// Mixing two different currencies is a programming error and panics: amounts must be converted first.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+// New returns an amount of minor units (e.g. cents) of the currency
+func New(minorUnits int64, currency Currency) Money {
+	return Money{Amount: minorUnits, Currency: currency}
+}
+
+// Parse reads a decimal amount of major units, such as "28.50", which must be exact to the minor unit
+func Parse(s string, currency Currency) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	minorUnits := new(big.Rat).Mul(r, new(big.Rat).SetInt(currency.minorUnitsPerMajor()))
+	if !minorUnits.IsInt() || !minorUnits.Num().IsInt64() {
+		return Money{}, fmt.Errorf("amount %q is not an exact number of %s minor units", s, currency)
+	}
+	return New(minorUnits.Num().Int64(), currency), nil
+}
+
+// MustParse is like Parse, but panics on invalid amounts; it is meant for constants
+func MustParse(s string, currency Currency) Money {
+	m, err := Parse(s, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromRat returns the amount of major units r, rounded to the minor unit with the given mode
+func FromRat(r *big.Rat, currency Currency, mode RoundingMode) Money {
+	minorUnits := new(big.Rat).Mul(r, new(big.Rat).SetInt(currency.minorUnitsPerMajor()))
+	return New(round(minorUnits, mode).Int64(), currency)
+}
+
+// Rat returns the exact amount of major units
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.Amount), m.Currency.minorUnitsPerMajor())
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of m
+func (m Money) Sign() int {
+	switch {
+	case m.Amount < 0:
+		return -1
+	case m.Amount > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) Add(o Money) Money {
+	currency := m.commonCurrency(o)
+	return New(m.Amount+o.Amount, currency)
+}
+
+func (m Money) Sub(o Money) Money {
+	currency := m.commonCurrency(o)
+	return New(m.Amount-o.Amount, currency)
+}
+
+func (m Money) Neg() Money {
+	return New(-m.Amount, m.Currency)
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or greater than o
+func (m Money) Cmp(o Money) int {
+	m.commonCurrency(o)
+	return m.Sub(o).Sign()
+}
+
+// MulRat multiplies m by r, and rounds the result to the minor unit with the given mode
+func (m Money) MulRat(r *big.Rat, mode RoundingMode) Money {
+	return FromRat(new(big.Rat).Mul(m.Rat(), r), m.Currency, mode)
+}
+
+// Decimal formats the amount of major units, with all the digits of the minor unit, e.g. "92.40" or "-15.31"
+func (m Money) Decimal() string {
+	return m.Rat().FloatString(m.Currency.MinorUnitDigits())
+}
+
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Decimal(), m.Currency)
+}
+
+// Sum adds up amounts, in the given currency
+func Sum(currency Currency, amounts ...Money) Money {
+	total := New(0, currency)
+	for _, amount := range amounts {
+		total = total.Add(amount)
+	}
+	return total
+}
+
+// commonCurrency returns the currency of an operation on m and o, and panics if they are not compatible
+func (m Money) commonCurrency(o Money) Currency {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency
+	case m.Currency == "" && m.Amount == 0:
+		return o.Currency
+	case o.Currency == "" && o.Amount == 0:
+		return m.Currency
+	default:
+		panic(fmt.Sprintf("money: cannot mix %s and %s amounts", m.Currency, o.Currency))
+	}
+}
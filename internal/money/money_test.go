@@ -0,0 +1,142 @@
+package money
+
+import (
+	"math/big"
+	"testing"
+	"testing/quick"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		currency Currency
+		expected Money
+		wantErr  bool
+	}{
+		"cents":          {input: "28.50", currency: EUR, expected: New(2850, EUR)},
+		"integer":        {input: "35", currency: EUR, expected: New(3500, EUR)},
+		"negative":       {input: "-0.05", currency: EUR, expected: New(-5, EUR)},
+		"no minor unit":  {input: "1500", currency: JPY, expected: New(1500, JPY)},
+		"too precise":    {input: "0.125", currency: EUR, wantErr: true},
+		"fractional yen": {input: "1.5", currency: JPY, wantErr: true},
+		"not a number":   {input: "twelve", currency: EUR, wantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Parse(test.input, test.currency)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if actual != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMulRatRoundingModes(t *testing.T) {
+	// 0.25 EUR × 1/2 = 0.125 EUR, exactly between two cents
+	half := big.NewRat(1, 2)
+	tests := map[RoundingMode][2]int64{
+		HalfUp:   {13, -13},
+		HalfEven: {12, -12},
+		HalfDown: {12, -12},
+		Down:     {12, -12},
+		Up:       {13, -13},
+	}
+	for mode, expected := range tests {
+		if actual := New(25, EUR).MulRat(half, mode); actual.Amount != expected[0] {
+			t.Errorf("mode %d: expected %d, got %d", mode, expected[0], actual.Amount)
+		}
+		if actual := New(-25, EUR).MulRat(half, mode); actual.Amount != expected[1] {
+			t.Errorf("mode %d: expected %d, got %d", mode, expected[1], actual.Amount)
+		}
+	}
+
+	// 0.35 EUR × 1/2 = 0.175 EUR, half-even rounds to the even cent above
+	if actual := New(35, EUR).MulRat(half, HalfEven); actual.Amount != 18 {
+		t.Errorf("Expected 18, got %d", actual.Amount)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := map[Money]string{
+		New(9240, EUR):  "92.40",
+		New(-1531, EUR): "-15.31",
+		New(5, EUR):     "0.05",
+		New(0, EUR):     "0.00",
+		New(1500, JPY):  "1500",
+	}
+	for amount, expected := range tests {
+		if actual := amount.Decimal(); actual != expected {
+			t.Errorf("Expected %s, got %s", expected, actual)
+		}
+	}
+}
+
+func TestMixingCurrenciesPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic")
+		}
+	}()
+	New(100, EUR).Add(New(100, USD))
+}
+
+func TestZeroMoneyHasAnyCurrency(t *testing.T) {
+	var zero Money
+	if actual := zero.Add(New(100, USD)); actual != New(100, USD) {
+		t.Errorf("Expected 1.00 USD, got %v", actual)
+	}
+}
+
+func TestAddSubProperties(t *testing.T) {
+	inverse := func(a, b int32) bool {
+		x, y := New(int64(a), EUR), New(int64(b), EUR)
+		return x.Add(y).Sub(y) == x && x.Add(y) == y.Add(x)
+	}
+	if err := quick.Check(inverse, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMulRatProperties(t *testing.T) {
+	modes := []RoundingMode{HalfUp, HalfEven, HalfDown, Down, Up}
+
+	// whatever the mode, rounding moves the exact product by less than one minor unit,
+	// and nearest modes by at most half a minor unit
+	withinOneUnit := func(amount int32, num int16, den uint8, modeIndex uint8) bool {
+		mode := modes[int(modeIndex)%len(modes)]
+		ratio := big.NewRat(int64(num), int64(den)+1)
+		m := New(int64(amount), EUR)
+
+		exact := new(big.Rat).Mul(big.NewRat(int64(amount), 1), ratio)
+		diff := new(big.Rat).Sub(big.NewRat(m.MulRat(ratio, mode).Amount, 1), exact)
+		diff.Abs(diff)
+
+		limit := big.NewRat(1, 1)
+		if mode == HalfUp || mode == HalfEven || mode == HalfDown {
+			return diff.Cmp(big.NewRat(1, 2)) <= 0
+		}
+		return diff.Cmp(limit) < 0
+	}
+	if err := quick.Check(withinOneUnit, nil); err != nil {
+		t.Error(err)
+	}
+
+	// rounding is symmetric around zero
+	symmetric := func(amount int32, num int16, den uint8, modeIndex uint8) bool {
+		mode := modes[int(modeIndex)%len(modes)]
+		ratio := big.NewRat(int64(num), int64(den)+1)
+		return New(int64(amount), EUR).MulRat(ratio, mode) == New(-int64(amount), EUR).MulRat(ratio, mode).Neg()
+	}
+	if err := quick.Check(symmetric, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,53 @@
+package money
+
+import "math/big"
+
+// RoundingMode tells how amounts which fall between two minor units are rounded
+type RoundingMode int
+
+const (
+	// HalfUp rounds to the nearest minor unit, and halves away from zero: 0.125 → 0.13, -0.125 → -0.13
+	HalfUp RoundingMode = iota
+	// HalfEven rounds to the nearest minor unit, and halves to the even one (banker's rounding): 0.125 → 0.12
+	HalfEven
+	// HalfDown rounds to the nearest minor unit, and halves towards zero: 0.125 → 0.12
+	HalfDown
+	// Down rounds towards zero (truncation): 0.129 → 0.12, -0.129 → -0.12
+	Down
+	// Up rounds away from zero: 0.121 → 0.13, -0.121 → -0.13
+	Up
+)
+
+// round rounds r to an integer with the given mode
+func round(r *big.Rat, mode RoundingMode) *big.Int {
+	quotient, remainder := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// the remainder has the sign of r, the denominator is positive
+	awayFromZero := big.NewInt(int64(r.Sign()))
+	// compare twice the remainder to the denominator to know on which side of the half r is
+	half := new(big.Int).Abs(remainder)
+	half.Lsh(half, 1)
+	halfCmp := half.Cmp(r.Denom())
+
+	roundAway := false
+	switch mode {
+	case HalfUp:
+		roundAway = halfCmp >= 0
+	case HalfEven:
+		roundAway = halfCmp > 0 || (halfCmp == 0 && quotient.Bit(0) == 1)
+	case HalfDown:
+		roundAway = halfCmp > 0
+	case Down:
+		roundAway = false
+	case Up:
+		roundAway = true
+	}
+
+	if roundAway {
+		quotient.Add(quotient, awayFromZero)
+	}
+	return quotient
+}
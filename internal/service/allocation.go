@@ -0,0 +1,268 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+var ErrUnknownSeatAllocation = errors.New("unknown seat allocation strategy")
+
+// SeatAllocator chooses the seats of a reservation among the free seats of a room
+type SeatAllocator interface {
+	// AllocateSeats returns "count" contiguous free seats of the category in a same row, nil when there are none
+	AllocateSeats(room types.TheaterRoom, count int, category types.ZoneCategory) []string
+}
+
+// SeatAllocatorFunc adapts a function to a SeatAllocator
+type SeatAllocatorFunc func(room types.TheaterRoom, count int, category types.ZoneCategory) []string
+
+func (f SeatAllocatorFunc) AllocateSeats(room types.TheaterRoom, count int, category types.ZoneCategory) []string {
+	return f(room, count, category)
+}
+
+// defaultSeatAllocators returns the built-in seat allocation strategies
+func defaultSeatAllocators() map[types.SeatAllocation]SeatAllocator {
+	return map[types.SeatAllocation]SeatAllocator{
+		types.SeatAllocationFirstFit:         FirstFitAllocator(),
+		types.SeatAllocationCenter:           CenterAllocator(),
+		types.SeatAllocationClosestToStage:   ClosestToStageAllocator(),
+		types.SeatAllocationLeastFragmenting: LeastFragmentingAllocator(),
+	}
+}
+
+// seatAllocator returns the allocator of the strategy of the request, or of its performance when the request sets none
+func (t *TheaterService) seatAllocator(request types.ReservationRequest) (SeatAllocator, error) {
+	name := request.SeatAllocation
+	if name == "" {
+		name = request.Performance.SeatAllocation
+	}
+	if name == "" {
+		name = t.defaultSeatAllocation
+	}
+	allocator, ok := t.seatAllocators[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSeatAllocation, name)
+	}
+	return allocator, nil
+}
+
+// seatRun is a run of contiguous free seats which may be allocated
+type seatRun struct {
+	// depth is the rank of the row from the stage, counting the rows of all zones in declaration order
+	depth int
+	row   types.Row
+	// start is the index of the first seat of the run in the row
+	start int
+	count int
+	// blockStart and blockEnd bound the free seats around the run, blockEnd excluded
+	blockStart int
+	blockEnd   int
+}
+
+func (r seatRun) seatIDs() []string {
+	seatIDs := make([]string, 0, r.count)
+	for _, seat := range r.row.Seats[r.start : r.start+r.count] {
+		seatIDs = append(seatIDs, seat.SeatID)
+	}
+	return seatIDs
+}
+
+// lateralOffset returns how far the middle of the run is from the middle of its row, in seats
+func (r seatRun) lateralOffset() float64 {
+	return math.Abs(float64(r.start) + float64(r.count-1)/2 - float64(len(r.row.Seats)-1)/2)
+}
+
+func isFreeSeat(seat types.Seat) bool {
+	return seat.Status != types.SeatStatusBooked && seat.Status != types.SeatStatusBookingPending
+}
+
+// seatRuns lists the runs of "count" contiguous free seats of the category, row by row then from the first seat of the row
+func seatRuns(room types.TheaterRoom, count int, category types.ZoneCategory) []seatRun {
+	if count < 1 {
+		return nil
+	}
+	var runs []seatRun
+	depth := 0
+	for _, zone := range room.Zones {
+		for _, row := range zone.Rows {
+			depth++
+			if zone.Category != category {
+				continue
+			}
+			for blockStart := 0; blockStart < len(row.Seats); {
+				if !isFreeSeat(row.Seats[blockStart]) {
+					blockStart++
+					continue
+				}
+				blockEnd := blockStart
+				for blockEnd < len(row.Seats) && isFreeSeat(row.Seats[blockEnd]) {
+					blockEnd++
+				}
+				for start := blockStart; start+count <= blockEnd; start++ {
+					runs = append(runs, seatRun{
+						depth:      depth,
+						row:        row,
+						start:      start,
+						count:      count,
+						blockStart: blockStart,
+						blockEnd:   blockEnd,
+					})
+				}
+				blockStart = blockEnd
+			}
+		}
+	}
+	return runs
+}
+
+// bestSeatRun returns the seats of the best run according to compare, the first one listed on ties, nil when there are none
+func bestSeatRun(runs []seatRun, compare func(a, b seatRun) int) []string {
+	if len(runs) == 0 {
+		return nil
+	}
+	return slices.MinFunc(runs, compare).seatIDs()
+}
+
+// FirstFitAllocator takes the first contiguous seats found, scanning zones and rows in declaration order
+func FirstFitAllocator() SeatAllocator {
+	return SeatAllocatorFunc(func(room types.TheaterRoom, count int, category types.ZoneCategory) []string {
+		return bestSeatRun(seatRuns(room, count, category), func(a, b seatRun) int { return 0 })
+	})
+}
+
+// CenterAllocator takes the contiguous seats closest to the center of their row, the ones of the front rows on ties
+func CenterAllocator() SeatAllocator {
+	return SeatAllocatorFunc(func(room types.TheaterRoom, count int, category types.ZoneCategory) []string {
+		return bestSeatRun(seatRuns(room, count, category), func(a, b seatRun) int {
+			return cmp.Compare(a.lateralOffset(), b.lateralOffset())
+		})
+	})
+}
+
+// ClosestToStageAllocator takes the contiguous seats closest to the middle of the stage altogether.
+// Rows are centered on the stage and one row apart, the first one being a row away from the stage.
+func ClosestToStageAllocator() SeatAllocator {
+	distance := func(run seatRun) float64 {
+		var distance float64
+		middle := float64(len(run.row.Seats)-1) / 2
+		for i := run.start; i < run.start+run.count; i++ {
+			distance += math.Hypot(float64(run.depth), float64(i)-middle)
+		}
+		return distance
+	}
+	return SeatAllocatorFunc(func(room types.TheaterRoom, count int, category types.ZoneCategory) []string {
+		return bestSeatRun(seatRuns(room, count, category), func(a, b seatRun) int {
+			distanceA, distanceB := distance(a), distance(b)
+			// symmetric runs are as close, whatever the rounding of their sums
+			if math.Abs(distanceA-distanceB) < 1e-9 {
+				return 0
+			}
+			return cmp.Compare(distanceA, distanceB)
+		})
+	})
+}
+
+// LeastFragmentingAllocator takes the contiguous seats leaving the fewest isolated seats,
+// then the fewest free runs, in the free seats they are taken from; the first ones found on ties
+func LeastFragmentingAllocator() SeatAllocator {
+	fragments := func(run seatRun) (isolated int, runs int) {
+		for _, left := range []int{run.start - run.blockStart, run.blockEnd - run.start - run.count} {
+			if left == 1 {
+				isolated++
+			}
+			if left > 0 {
+				runs++
+			}
+		}
+		return isolated, runs
+	}
+	return SeatAllocatorFunc(func(room types.TheaterRoom, count int, category types.ZoneCategory) []string {
+		return bestSeatRun(seatRuns(room, count, category), func(a, b seatRun) int {
+			isolatedA, runsA := fragments(a)
+			isolatedB, runsB := fragments(b)
+			if isolatedA != isolatedB {
+				return isolatedA - isolatedB
+			}
+			return runsA - runsB
+		})
+	})
+}
+
+// splitSeats offers "count" free seats of the category which are not all side by side, nil when there are not enough of them.
+// It prefers seats in the same column range of adjacent rows of a zone, as few rows as possible,
+// then takes the first free seats found row by row.
+// Columns are the ranks of the seats in their rows.
+func splitSeats(room types.TheaterRoom, count int, category types.ZoneCategory) []string {
+	if count < 2 {
+		return nil
+	}
+	var zones []types.Zone
+	maxRows := 0
+	for _, zone := range room.Zones {
+		if zone.Category == category {
+			zones = append(zones, zone)
+			maxRows = max(maxRows, len(zone.Rows))
+		}
+	}
+
+	for rowCount := 2; rowCount <= min(count, maxRows); rowCount++ {
+		width := (count + rowCount - 1) / rowCount
+		for _, zone := range zones {
+			for first := 0; first+rowCount <= len(zone.Rows); first++ {
+				rows := zone.Rows[first : first+rowCount]
+				for column := 0; column+width <= maxRowSeats(rows); column++ {
+					if seats := columnRangeSeats(rows, column, width, count); seats != nil {
+						return seats
+					}
+				}
+			}
+		}
+	}
+
+	var seats []string
+	for _, zone := range zones {
+		for _, row := range zone.Rows {
+			for _, seat := range row.Seats {
+				if isFreeSeat(seat) && len(seats) < count {
+					seats = append(seats, seat.SeatID)
+				}
+			}
+		}
+	}
+	if len(seats) < count {
+		return nil
+	}
+	return seats
+}
+
+// columnRangeSeats takes "count" free seats in the columns [column, column+width) of the rows, front rows first,
+// nil when some of them are taken or missing
+func columnRangeSeats(rows []types.Row, column int, width int, count int) []string {
+	var seats []string
+	for _, row := range rows {
+		take := min(width, count-len(seats))
+		if column+take > len(row.Seats) {
+			return nil
+		}
+		for _, seat := range row.Seats[column : column+take] {
+			if !isFreeSeat(seat) {
+				return nil
+			}
+			seats = append(seats, seat.SeatID)
+		}
+	}
+	return seats
+}
+
+func maxRowSeats(rows []types.Row) int {
+	maxSeats := 0
+	for _, row := range rows {
+		maxSeats = max(maxSeats, len(row.Seats))
+	}
+	return maxSeats
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// sketchRoom returns the room drawn in theater_sketch.txt, a single standard zone whose seats are free except the booked ones
+func sketchRoom(t *testing.T, bookedSeats ...string) types.TheaterRoom {
+	t.Helper()
+	file, err := os.Open("testdata/theater_sketch.txt")
+	if err != nil {
+		t.Fatalf("Failed to open sketch: %v", err)
+	}
+	defer file.Close()
+
+	zone := types.Zone{Category: types.ZoneCategoryStandard}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "//") || strings.Contains(line, "stage") {
+			continue
+		}
+		var row types.Row
+		for _, seatID := range strings.Fields(line) {
+			status := types.SeatStatus(types.SeatStatusFree)
+			if slices.Contains(bookedSeats, seatID) {
+				status = types.SeatStatusBooked
+			}
+			row.Seats = append(row.Seats, types.Seat{SeatID: seatID, Status: status})
+		}
+		zone.Rows = append(zone.Rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Failed to read sketch: %v", err)
+	}
+	return types.TheaterRoom{Zones: []types.Zone{zone}}
+}
+
+func TestSeatAllocators(t *testing.T) {
+	// booked as for performance 1
+	bookedSeats := []string{"A1", "A3", "A4", "B2"}
+
+	tests := []struct {
+		allocation types.SeatAllocation
+		// expected seats in the empty room, then with the booked seats
+		expectedEmpty []string
+		expectedPairs []string
+		expectedThree []string
+	}{
+		{types.SeatAllocationFirstFit, []string{"A1", "A2", "A3", "A4"}, []string{"A5", "A6"}, []string{"A5", "A6", "A7"}},
+		// B3-B6 are in the very middle of row B, C4-C6 in the middle of row C
+		{types.SeatAllocationCenter, []string{"B3", "B4", "B5", "B6"}, []string{"B4", "B5"}, []string{"C4", "C5", "C6"}},
+		// A5-A7 are far on the side of row A
+		{types.SeatAllocationClosestToStage, []string{"A2", "A3", "A4", "A5"}, []string{"A5", "A6"}, []string{"B3", "B4", "B5"}},
+		// A5-A6 would leave A7 alone
+		{types.SeatAllocationLeastFragmenting, []string{"A1", "A2", "A3", "A4"}, []string{"B3", "B4"}, []string{"A5", "A6", "A7"}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.allocation), func(t *testing.T) {
+			allocator := defaultSeatAllocators()[test.allocation]
+			for _, c := range []struct {
+				room     types.TheaterRoom
+				count    int
+				expected []string
+			}{
+				{sketchRoom(t), 4, test.expectedEmpty},
+				{sketchRoom(t, bookedSeats...), 2, test.expectedPairs},
+				{sketchRoom(t, bookedSeats...), 3, test.expectedThree},
+			} {
+				seats := allocator.AllocateSeats(c.room, c.count, types.ZoneCategoryStandard)
+				if !slices.Equal(seats, c.expected) {
+					t.Errorf("Expected seats %v for %d seats, got %v", c.expected, c.count, seats)
+				}
+			}
+
+			// no row has 11 seats, and there are no premium seats
+			if seats := allocator.AllocateSeats(sketchRoom(t), 11, types.ZoneCategoryStandard); seats != nil {
+				t.Errorf("Expected no seats for 11 seats, got %v", seats)
+			}
+			if seats := allocator.AllocateSeats(sketchRoom(t), 1, types.ZoneCategoryPremium); seats != nil {
+				t.Errorf("Expected no premium seats, got %v", seats)
+			}
+		})
+	}
+}
+
+func TestSeatAllocationSelection(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime, SeatAllocation: types.SeatAllocationCenter}
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+	// the strategy of the performance applies
+	result := service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 3, Category: types.ZoneCategoryStandard, Performance: performance})
+	if result.Err != nil || !slices.Equal(result.Seats, []string{"C4", "C5", "C6"}) {
+		t.Errorf("Expected seats C4-C6, got %v (%v)", result.Seats, result.Err)
+	}
+
+	// the one of the request overrides it
+	result = service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance,
+		SeatAllocation: types.SeatAllocationLeastFragmenting})
+	if result.Err != nil || !slices.Equal(result.Seats, []string{"B3", "B4"}) {
+		t.Errorf("Expected seats B3-B4, got %v (%v)", result.Seats, result.Err)
+	}
+
+	// the default one of the service applies when none is set
+	performance.SeatAllocation = ""
+	service = NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock),
+		WithDefaultSeatAllocation(types.SeatAllocationClosestToStage))
+	result = service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 3, Category: types.ZoneCategoryStandard, Performance: performance})
+	if result.Err != nil || !slices.Equal(result.Seats, []string{"B3", "B4", "B5"}) {
+		t.Errorf("Expected seats B3-B5, got %v (%v)", result.Seats, result.Err)
+	}
+
+	result = service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 3, Category: types.ZoneCategoryStandard, Performance: performance,
+		SeatAllocation: "RANDOM"})
+	if !errors.Is(result.Err, ErrUnknownSeatAllocation) || result.Status != types.ReservationStatusAborted {
+		t.Errorf("Expected unknown seat allocation, got %v (%v)", result.Status, result.Err)
+	}
+}
+
+func TestSplitSeats(t *testing.T) {
+	// every third seat is booked, so that no row has 3 contiguous free seats
+	var bookedSeats []string
+	for _, row := range "ABCDEFG" {
+		for _, number := range []string{"3", "6", "9"} {
+			bookedSeats = append(bookedSeats, string(row)+number)
+		}
+	}
+	room := sketchRoom(t, bookedSeats...)
+
+	tests := []struct {
+		count    int
+		expected []string
+	}{
+		{3, []string{"A1", "A2", "B1"}},
+		{4, []string{"A1", "A2", "B1", "B2"}},
+		// 2 rows would need 4 seats side by side, 3 rows would need 3
+		{7, []string{"A1", "A2", "B1", "B2", "C1", "C2", "D1"}},
+		{1, nil},
+		{100, nil},
+	}
+	for _, test := range tests {
+		seats := splitSeats(room, test.count, types.ZoneCategoryStandard)
+		if !slices.Equal(seats, test.expected) {
+			t.Errorf("Expected seats %v for %d seats, got %v", test.expected, test.count, seats)
+		}
+	}
+
+	// no free seats in the same column of the two rows
+	room = types.TheaterRoom{Zones: []types.Zone{{Category: types.ZoneCategoryStandard, Rows: []types.Row{
+		{Seats: []types.Seat{{SeatID: "X1", Status: types.SeatStatusFree}, {SeatID: "X2", Status: types.SeatStatusBooked}, {SeatID: "X3", Status: types.SeatStatusFree}}},
+		{Seats: []types.Seat{{SeatID: "Y1", Status: types.SeatStatusBookingPending}, {SeatID: "Y2", Status: types.SeatStatusFree}, {SeatID: "Y3", Status: types.SeatStatusBooked}}},
+	}}}}
+	seats := splitSeats(room, 3, types.ZoneCategoryStandard)
+	if !slices.Equal(seats, []string{"X1", "X3", "Y2"}) {
+		t.Errorf("Expected seats X1, X3 and Y2, got %v", seats)
+	}
+}
+
+func TestSplitSeating(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	request := types.ReservationRequest{CustomerID: 1, ReservationCount: 11, Category: types.ZoneCategoryStandard, Performance: performance}
+	// B2 is booked
+	expectedSeats := []string{"B3", "B4", "B5", "B6", "B7", "B8", "C3", "C4", "C5", "C6", "C7"}
+
+	// no row has 11 seats
+	result := service.Reserve(request)
+	if !errors.Is(result.Err, ErrNoSeatsAvailable) || result.SplitOffer != nil {
+		t.Errorf("Expected no seats and no offer, got %v (%v)", result.SplitOffer, result.Err)
+	}
+
+	request.SplitSeating = types.SplitSeatingReject
+	result = service.Reserve(request)
+	if !errors.Is(result.Err, ErrNoSeatsAvailable) || result.Status != types.ReservationStatusAborted {
+		t.Errorf("Expected the reservation to be aborted, got %v (%v)", result.Status, result.Err)
+	}
+	if !slices.Equal(result.SplitOffer, expectedSeats) {
+		t.Errorf("Expected offer of seats %v, got %v", expectedSeats, result.SplitOffer)
+	}
+
+	// the seats offered were not held
+	request.SplitSeating = types.SplitSeatingAccept
+	result = service.Reserve(request)
+	if result.Err != nil || result.Status != types.ReservationStatusFulfillable {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	if !slices.Equal(result.Seats, expectedSeats) || result.Contiguous() || !result.Reservation.SplitSeats {
+		t.Errorf("Expected split seats %v, got %v (contiguous: %t)", expectedSeats, result.Seats, result.Contiguous())
+	}
+
+	// contiguous seats are preferred
+	result = service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance,
+		SplitSeating: types.SplitSeatingAccept})
+	if result.Err != nil || !slices.Equal(result.Seats, []string{"A5", "A6"}) || !result.Contiguous() {
+		t.Errorf("Expected contiguous seats A5-A6, got %v (%v)", result.Seats, result.Err)
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+var (
+	ErrDuplicateSeatID   = errors.New("seat chosen several times")
+	ErrSeatsNotAvailable = errors.New("chosen seats cannot be reserved")
+)
+
+// checkSeatIDs returns an error when the customer chose a seat several times
+func checkSeatIDs(request types.ReservationRequest) error {
+	chosen := make(map[string]bool, len(request.SeatIDs))
+	for _, seatID := range request.SeatIDs {
+		if chosen[seatID] {
+			return fmt.Errorf("%w: %s", ErrDuplicateSeatID, seatID)
+		}
+		chosen[seatID] = true
+	}
+	return nil
+}
+
+// findChosenSeats checks the seats chosen by the customer can be reserved, they are all found or none of them.
+// Free seats are kept for VIPs once the ones chosen before reach the quota of the performance.
+func (t *TheaterService) findChosenSeats(room types.TheaterRoom, request types.ReservationRequest, quota vipQuota) seatSearch {
+	var search seatSearch
+	rejections := make(map[string]types.SeatRejection)
+	var freeSeats []string
+	seatsCategory := make(map[string]types.ZoneCategory)
+	for _, seatID := range request.SeatIDs {
+		seat, category, ok := room.FindSeat(seatID)
+		switch {
+		case !ok:
+			rejections[seatID] = types.SeatRejectionUnknown
+		case seat.Status == types.SeatStatusBooked:
+			rejections[seatID] = types.SeatRejectionBooked
+		case seat.Status == types.SeatStatusBookingPending:
+			rejections[seatID] = types.SeatRejectionPending
+		default:
+			seatsCategory[seatID] = category
+			if quota.check(room, seatsCategory) != nil {
+				delete(seatsCategory, seatID)
+				rejections[seatID] = types.SeatRejectionVIPReserved
+				continue
+			}
+			freeSeats = append(freeSeats, seatID)
+		}
+	}
+	if len(rejections) > 0 {
+		search.rejections = rejections
+		return search
+	}
+
+	search.foundSeats = freeSeats
+	search.seatsCategory = seatsCategory
+	search.foundAllSeats = len(freeSeats) > 0
+	return search
+}
+
+// seatRejectionsError describes why the chosen seats cannot be reserved, in the order of the request
+func seatRejectionsError(request types.ReservationRequest, rejections map[string]types.SeatRejection) error {
+	var reasons []string
+	for _, seatID := range request.SeatIDs {
+		if rejection, ok := rejections[seatID]; ok {
+			reasons = append(reasons, fmt.Sprintf("%s %s", seatID, strings.ToLower(strings.ReplaceAll(string(rejection), "_", " "))))
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrSeatsNotAvailable, strings.Join(reasons, ", "))
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestChosenSeats(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	roomsDAO := dao.NewTheaterRoomsDAO()
+	service := NewTheaterService(dao.NewReservationDAO(), roomsDAO, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+	// seats of both categories, each priced by the category of its zone
+	result := service.Reserve(types.ReservationRequest{CustomerID: 1, Performance: performance, SeatIDs: []string{"C4", "C5", "H2"}})
+	if result.Err != nil || result.Status != types.ReservationStatusFulfillable {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	if !slices.Equal(result.Seats, []string{"C4", "C5", "H2"}) || result.Request.ReservationCount != 3 {
+		t.Errorf("Expected seats C4, C5 and H2, got %v", result.Seats)
+	}
+	expectedCategories := map[string]types.ZoneCategory{"C4": types.ZoneCategoryStandard, "C5": types.ZoneCategoryStandard, "H2": types.ZoneCategoryPremium}
+	if !maps.Equal(result.SeatCategories, expectedCategories) {
+		t.Errorf("Expected seat categories %v, got %v", expectedCategories, result.SeatCategories)
+	}
+	var surcharges []string
+	for _, line := range result.Price.Lines {
+		if line.Kind == types.PriceLineCategorySurcharge {
+			surcharges = append(surcharges, line.SeatID)
+		}
+	}
+	if !slices.Equal(surcharges, []string{"H2"}) {
+		t.Errorf("Expected a premium surcharge for H2 only, got %v", surcharges)
+	}
+
+	// no seat is held unless all of them can be
+	result = service.Reserve(types.ReservationRequest{CustomerID: 2, Performance: performance, SeatIDs: []string{"C9", "A1", "C4", "Z9"}})
+	if !errors.Is(result.Err, ErrSeatsNotAvailable) || result.Status != types.ReservationStatusAborted || len(result.Seats) > 0 {
+		t.Fatalf("Expected the reservation to be aborted, got %v (%v)", result.Status, result.Err)
+	}
+	expectedRejections := map[string]types.SeatRejection{"A1": types.SeatRejectionBooked, "C4": types.SeatRejectionPending, "Z9": types.SeatRejectionUnknown}
+	if !maps.Equal(result.SeatRejections, expectedRejections) {
+		t.Errorf("Expected seat rejections %v, got %v", expectedRejections, result.SeatRejections)
+	}
+	if result.Err.Error() != "chosen seats cannot be reserved: A1 booked, C4 pending, Z9 unknown" {
+		t.Errorf("Unexpected error message: %v", result.Err)
+	}
+	room, err := roomsDAO.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	if seat, _, _ := room.FindSeat("C9"); seat.Status != types.SeatStatusFree {
+		t.Errorf("Expected C9 to be left free, got %s", seat.Status)
+	}
+
+	result = service.Reserve(types.ReservationRequest{CustomerID: 2, Performance: performance, SeatIDs: []string{"C9", "C9"}})
+	if !errors.Is(result.Err, ErrDuplicateSeatID) {
+		t.Errorf("Expected ErrDuplicateSeatID, got %v", result.Err)
+	}
+}
+
+func TestChosenSeatsVIPReserved(t *testing.T) {
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	request := types.ReservationRequest{CustomerID: 2, Performance: performanceScala, SeatIDs: []string{"C4"}}
+
+	result := service.Reserve(request)
+	if result.SeatRejections["C4"] != types.SeatRejectionVIPReserved {
+		t.Fatalf("Expected C4 to be kept for VIPs, got %v (%v)", result.SeatRejections, result.Err)
+	}
+	// patrons may book the seats kept for VIPs
+	request.CustomerID = 5
+	result = service.Reserve(request)
+	if result.Err != nil || !slices.Equal(result.Seats, []string{"C4"}) {
+		t.Errorf("Expected patron to book C4, got %v (%v)", result.Seats, result.Err)
+	}
+}
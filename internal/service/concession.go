@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+var ErrNotEligible = errors.New("customer is not eligible to the concession ticket type")
+
+// checkEligibility returns the eligibility of the customer to the ticket type of each seat to reserve,
+// it fails with ErrNotEligible when the customer is known not to be eligible to one of them
+func (t *TheaterService) checkEligibility(request types.ReservationRequest) ([]types.Eligibility, error) {
+	eligibility := make([]types.Eligibility, len(request.TicketTypes))
+	for i, ticketType := range request.TicketTypes {
+		var err error
+		eligibility[i], err = t.eligibilityDAO.FetchEligibility(request.CustomerID, ticketType)
+		if err != nil {
+			return nil, fmt.Errorf("fetch eligibility: %w", err)
+		}
+		if eligibility[i] == types.EligibilityIneligible {
+			return nil, fmt.Errorf("%w: %s", ErrNotEligible, ticketType)
+		}
+	}
+	return eligibility, nil
+}
+
+// seatEligibility associates the concession seats found for a request to their eligibility, in order;
+// it is nil when there is no concession seat
+func seatEligibility(eligibility []types.Eligibility, seats []string) map[string]types.Eligibility {
+	var seatsEligibility map[string]types.Eligibility
+	for i, seat := range seats {
+		if i >= len(eligibility) || eligibility[i] == types.EligibilityNotRequired {
+			continue
+		}
+		if seatsEligibility == nil {
+			seatsEligibility = make(map[string]types.Eligibility)
+		}
+		seatsEligibility[seat] = eligibility[i]
+	}
+	return seatsEligibility
+}
+
+// DoorCheckList returns the concession seats booked or held for the performance, by reservation,
+// for the staff checking the proofs of eligibility at the door
+func (t *TheaterService) DoorCheckList(performanceID int64) ([]types.DoorCheckEntry, error) {
+	reservations, err := t.reservationService.reservationDAO.FindByPerformance(performanceID)
+	if err != nil {
+		return nil, fmt.Errorf("find reservations: %w", err)
+	}
+
+	var entries []types.DoorCheckEntry
+	for _, reservation := range reservations {
+		if reservation.Status != types.ReservationStatusPending && reservation.Status != types.ReservationStatusConfirmed {
+			continue
+		}
+		for _, seat := range reservation.Seats {
+			eligibility, ok := reservation.SeatEligibility[seat]
+			if !ok {
+				continue
+			}
+			entries = append(entries, types.DoorCheckEntry{
+				ReservationID: reservation.ReservationID,
+				CustomerID:    reservation.CustomerID,
+				SeatID:        seat,
+				Category:      reservation.SeatCategories[seat],
+				TicketType:    reservation.SeatTicketTypes[seat],
+				Eligibility:   eligibility,
+			})
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestConcessions(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+	// customer #3 has already proven to be a student
+	student := service.Reserve(types.ReservationRequest{CustomerID: 3, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance,
+		TicketTypes: []types.TicketType{types.TicketTypeAdult, types.TicketTypeStudent}})
+	if student.Err != nil {
+		t.Fatalf("Failed to reserve: %v", student.Err)
+	}
+	adultSeat, studentSeat := student.Seats[0], student.Seats[1]
+	if _, ok := student.SeatEligibility[adultSeat]; ok || student.Reservation.SeatEligibility[studentSeat] != types.EligibilityVerified {
+		t.Errorf("Expected only the student seat to be flagged as verified, got %v", student.Reservation.SeatEligibility)
+	}
+	// 35.00 + 21.00, then 20% off with the voucher program
+	if student.Price.TotalAmountDue != money.MustParse("44.80", money.EUR) {
+		t.Errorf("Expected total amount due of 44.80, got %v", student.Price.TotalAmountDue)
+	}
+
+	senior := service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryPremium, Performance: performance,
+		TicketTypes: []types.TicketType{types.TicketTypeSenior}})
+	if senior.Err != nil {
+		t.Fatalf("Failed to reserve: %v", senior.Err)
+	}
+
+	// customer #6 student card has expired
+	result := service.Reserve(types.ReservationRequest{CustomerID: 6, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performance,
+		TicketTypes: []types.TicketType{types.TicketTypeStudent}})
+	if !errors.Is(result.Err, ErrNotEligible) {
+		t.Errorf("Expected ErrNotEligible, got %v", result.Err)
+	}
+	result = service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryPremium, Performance: performance,
+		TicketTypes: []types.TicketType{types.TicketTypeUnemployed}})
+	if !errors.Is(result.Err, ErrTicketTypeNotSold) {
+		t.Errorf("Expected ErrTicketTypeNotSold, got %v", result.Err)
+	}
+
+	entries, err := service.DoorCheckList(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to list door checks: %v", err)
+	}
+	expected := []types.DoorCheckEntry{
+		{ReservationID: student.Reservation.ReservationID, CustomerID: 3, SeatID: studentSeat, Category: types.ZoneCategoryStandard, TicketType: types.TicketTypeStudent, Eligibility: types.EligibilityVerified},
+		{ReservationID: senior.Reservation.ReservationID, CustomerID: 2, SeatID: senior.Seats[0], Category: types.ZoneCategoryPremium, TicketType: types.TicketTypeSenior, Eligibility: types.EligibilityCheckAtDoor},
+	}
+	if len(entries) != len(expected) || entries[0] != expected[0] || entries[1] != expected[1] {
+		t.Errorf("Unexpected door checks, expected %+v, got %+v", expected, entries)
+	}
+
+	// cancelled seats are not checked at the door
+	err = service.CancelReservation(2, senior.Reservation.ReservationID)
+	if err != nil {
+		t.Fatalf("Failed to cancel: %v", err)
+	}
+	entries, err = service.DoorCheckList(performance.ID)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected 1 door check after cancellation, got %+v (%v)", entries, err)
+	}
+}
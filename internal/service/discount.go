@@ -0,0 +1,179 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// DiscountContext is what pricing rules know of the reservation they may grant a discount to
+type DiscountContext struct {
+	Request          types.ReservationRequest
+	PerformancePrice types.PerformancePrice
+	SubscriptionTier types.SubscriptionTier
+	BookedAt         time.Time
+}
+
+// DiscountRule is a pricing rule which may grant a discount to a reservation
+type DiscountRule interface {
+	// Discount returns the discount granted to the reservation, nil when the rule does not apply
+	Discount(ctx DiscountContext) (*types.Discount, error)
+}
+
+// DiscountRuleFunc is a DiscountRule implemented by a function
+type DiscountRuleFunc func(ctx DiscountContext) (*types.Discount, error)
+
+func (f DiscountRuleFunc) Discount(ctx DiscountContext) (*types.Discount, error) {
+	return f(ctx)
+}
+
+// SubscriberDiscountRule grants the discount of the subscription tier of the customer
+func SubscriberDiscountRule(priority int, stacking types.StackingMode) DiscountRule {
+	return DiscountRuleFunc(func(ctx DiscountContext) (*types.Discount, error) {
+		ratio := ctx.SubscriptionTier.Benefits().Discount
+		if ratio.Sign() == 0 {
+			return nil, nil
+		}
+		return &types.Discount{
+			Kind:     types.PriceLineSubscriberDiscount,
+			Rule:     string(ctx.SubscriptionTier),
+			Ratio:    ratio,
+			Priority: priority,
+			Stacking: stacking,
+		}, nil
+	})
+}
+
+// VoucherProgramRule grants the discount of the voucher program running at booking time
+func VoucherProgramRule(voucherProgramDAO dao.VoucherProgramRepository, priority int, stacking types.StackingMode) DiscountRule {
+	return DiscountRuleFunc(func(ctx DiscountContext) (*types.Discount, error) {
+		ratio, err := voucherProgramDAO.FetchVoucherProgram(ctx.BookedAt.UTC())
+		if err != nil {
+			return nil, fmt.Errorf("fetch voucher program: %w", err)
+		}
+		if ratio == nil || ratio.Sign() == 0 {
+			return nil, nil
+		}
+		return &types.Discount{
+			Kind:     types.PriceLineVoucherDiscount,
+			Ratio:    ratio,
+			Priority: priority,
+			Stacking: stacking,
+		}, nil
+	})
+}
+
+// GroupDiscountRule grants the largest discount of the group pricings of the performance matching the reservation
+func GroupDiscountRule(priority int, stacking types.StackingMode) DiscountRule {
+	return DiscountRuleFunc(func(ctx DiscountContext) (*types.Discount, error) {
+		ticketTypes := ctx.Request.SeatTicketTypes()
+		var discount *types.Discount
+		for _, group := range ctx.PerformancePrice.Groups {
+			if !group.Matches(ticketTypes) || (discount != nil && group.Discount.Cmp(discount.Ratio) <= 0) {
+				continue
+			}
+			discount = &types.Discount{
+				Kind:     types.PriceLineGroupDiscount,
+				Rule:     group.Name,
+				Ratio:    group.Discount,
+				Priority: priority,
+				Stacking: stacking,
+			}
+		}
+		return discount, nil
+	})
+}
+
+// defaultDiscountRules apply the group discount, the subscriber discount, then the voucher program discount to the price left
+func defaultDiscountRules(voucherProgramDAO dao.VoucherProgramRepository) []DiscountRule {
+	return []DiscountRule{
+		GroupDiscountRule(30, types.StackingMultiply),
+		SubscriberDiscountRule(20, types.StackingMultiply),
+		VoucherProgramRule(voucherProgramDAO, 10, types.StackingMultiply),
+	}
+}
+
+// stackDiscounts decides which discounts apply, and the share of the initial price each of them removes.
+//
+// Discounts are considered by decreasing priority: when one of them is exclusive, it applies alone,
+// otherwise only the largest best-only discount applies, along with all the multiply and additive ones.
+// Once the cap is reached, the discount crossing it is reduced and the next ones are suppressed.
+func stackDiscounts(discounts []types.Discount, discountCap *big.Rat) []types.DiscountOutcome {
+	discounts = slices.Clone(discounts)
+	slices.SortStableFunc(discounts, func(a, b types.Discount) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+
+	outcomes := make([]types.DiscountOutcome, len(discounts))
+	for i, discount := range discounts {
+		outcomes[i] = types.DiscountOutcome{Discount: discount, Status: types.DiscountStatusApplied, Share: new(big.Rat)}
+	}
+	suppress := func(i int, reason string) {
+		outcomes[i].Status = types.DiscountStatusSuppressed
+		outcomes[i].Reason = reason
+	}
+
+	exclusive := slices.IndexFunc(discounts, func(discount types.Discount) bool {
+		return discount.Stacking == types.StackingExclusive
+	})
+	if exclusive >= 0 {
+		for i := range discounts {
+			if i != exclusive {
+				suppress(i, "exclusive "+discounts[exclusive].Name())
+			}
+		}
+	} else {
+		best := -1
+		for i, discount := range discounts {
+			if discount.Stacking == types.StackingBestOnly && (best < 0 || discount.Ratio.Cmp(discounts[best].Ratio) > 0) {
+				best = i
+			}
+		}
+		for i, discount := range discounts {
+			if discount.Stacking == types.StackingBestOnly && i != best {
+				suppress(i, "best of "+discounts[best].Name())
+			}
+		}
+	}
+
+	one := big.NewRat(1, 1)
+	left := big.NewRat(1, 1) // ratio of the initial price left to pay
+	for i, discount := range discounts {
+		if outcomes[i].Status == types.DiscountStatusSuppressed {
+			continue
+		}
+		if discountCap != nil && new(big.Rat).Sub(one, left).Cmp(discountCap) >= 0 {
+			suppress(i, "cap reached")
+			continue
+		}
+
+		share := new(big.Rat).Set(discount.Ratio)
+		if discount.Stacking != types.StackingAdditive {
+			share.Mul(share, left)
+		}
+		share = minRat(share, left)
+		if discountCap != nil {
+			remaining := new(big.Rat).Sub(discountCap, new(big.Rat).Sub(one, left))
+			if share.Cmp(remaining) > 0 {
+				share = remaining
+				outcomes[i].Status = types.DiscountStatusCapped
+				outcomes[i].Reason = "cap reached"
+			}
+		}
+		outcomes[i].Share = share
+		left.Sub(left, share)
+	}
+	return outcomes
+}
+
+func minRat(a, b *big.Rat) *big.Rat {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return new(big.Rat).Set(b)
+}
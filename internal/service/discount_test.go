@@ -0,0 +1,100 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestDiscountStacking(t *testing.T) {
+	voucherProgramDAO := dao.NewVoucherProgramDAO()
+	rules := func(subscriberStacking, voucherStacking types.StackingMode) Option {
+		return WithDiscountRules(SubscriberDiscountRule(20, subscriberStacking), VoucherProgramRule(voucherProgramDAO, 10, voucherStacking))
+	}
+
+	// a gold subscriber books 4 seats at 35.00 while the voucher program runs: 17.5% and 20% off 140.00
+	tests := []struct {
+		name           string
+		options        []Option
+		totalAmountDue string
+		statuses       []types.DiscountStatus
+	}{
+		{
+			name:           "default",
+			totalAmountDue: "92.40",
+			statuses:       []types.DiscountStatus{types.DiscountStatusApplied, types.DiscountStatusApplied},
+		},
+		{
+			name:           "additive",
+			options:        []Option{rules(types.StackingAdditive, types.StackingAdditive)},
+			totalAmountDue: "87.50",
+			statuses:       []types.DiscountStatus{types.DiscountStatusApplied, types.DiscountStatusApplied},
+		},
+		{
+			name:           "best only",
+			options:        []Option{rules(types.StackingBestOnly, types.StackingBestOnly)},
+			totalAmountDue: "112.00",
+			statuses:       []types.DiscountStatus{types.DiscountStatusSuppressed, types.DiscountStatusApplied},
+		},
+		{
+			name:           "exclusive",
+			options:        []Option{rules(types.StackingExclusive, types.StackingMultiply)},
+			totalAmountDue: "115.50",
+			statuses:       []types.DiscountStatus{types.DiscountStatusApplied, types.DiscountStatusSuppressed},
+		},
+		{
+			name:           "capped at 30%",
+			options:        []Option{WithDiscountCap(big.NewRat(30, 100))},
+			totalAmountDue: "98.00",
+			statuses:       []types.DiscountStatus{types.DiscountStatusApplied, types.DiscountStatusCapped},
+		},
+		{
+			name:           "capped at 15%",
+			options:        []Option{WithDiscountCap(big.NewRat(15, 100))},
+			totalAmountDue: "119.00",
+			statuses:       []types.DiscountStatus{types.DiscountStatusCapped, types.DiscountStatusSuppressed},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := append([]Option{WithClock(bookingClock)}, test.options...)
+			service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), voucherProgramDAO, dao.NewCustomerSubscriptionDAO(), false, options...)
+			price, err := service.Quote(types.ReservationRequest{CustomerID: 1, ReservationCount: 4, Category: types.ZoneCategoryStandard, Performance: performanceCICD})
+			if err != nil {
+				t.Fatalf("Failed to quote: %v", err)
+			}
+			if price.TotalAmountDue != money.MustParse(test.totalAmountDue, money.EUR) {
+				t.Errorf("Expected total amount due of %s, got %v", test.totalAmountDue, price.TotalAmountDue)
+			}
+			if len(price.DiscountOutcomes) != len(test.statuses) {
+				t.Fatalf("Expected %d discount outcomes, got %+v", len(test.statuses), price.DiscountOutcomes)
+			}
+			// the subscriber discount has the highest priority
+			for i, outcome := range price.DiscountOutcomes {
+				if outcome.Status != test.statuses[i] {
+					t.Errorf("Expected %s to be %s, got %s (%s)", outcome.Discount.Name(), test.statuses[i], outcome.Status, outcome.Reason)
+				}
+				if outcome.Status != types.DiscountStatusApplied && outcome.Reason == "" {
+					t.Errorf("Expected %s to tell why it is %s", outcome.Discount.Name(), outcome.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestExclusiveDiscountPriority(t *testing.T) {
+	discounts := []types.Discount{
+		{Kind: types.PriceLineVoucherDiscount, Ratio: big.NewRat(1, 2), Priority: 1, Stacking: types.StackingExclusive},
+		{Kind: types.PriceLineSubscriberDiscount, Rule: "GOLD", Ratio: big.NewRat(1, 10), Priority: 5, Stacking: types.StackingExclusive},
+	}
+	outcomes := stackDiscounts(discounts, nil)
+	if outcomes[0].Discount.Kind != types.PriceLineSubscriberDiscount || outcomes[0].Status != types.DiscountStatusApplied {
+		t.Errorf("Expected the exclusive discount of highest priority to apply, got %+v", outcomes[0])
+	}
+	if outcomes[1].Status != types.DiscountStatusSuppressed || outcomes[1].Reason != "exclusive SUBSCRIBER_DISCOUNT GOLD" {
+		t.Errorf("Expected the other exclusive discount to be suppressed, got %+v", outcomes[1])
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// ReleaseExpiredHolds returns the seats of the pending reservations whose hold has expired to FREE,
+// and aborts these reservations. It returns the IDs of the aborted reservations.
+func (t *TheaterService) ReleaseExpiredHolds() ([]int64, error) {
+	pendingReservations, err := t.reservationService.FindByStatus(types.ReservationStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("find pending reservations: %w", err)
+	}
+
+	now := t.clock.Now()
+	var releasedIDs []int64
+	for _, pending := range pendingReservations {
+		if !isHoldExpired(pending, now) {
+			continue
+		}
+
+		released := false
+		err = t.transactor.Transaction(func(rooms dao.TheaterRoomRepository, reservations dao.ReservationRepository, ledger dao.LoyaltyLedgerRepository) error {
+			// the reservation may have been confirmed or cancelled since it was listed
+			reservation, err := reservations.Find(pending.ReservationID)
+			if err != nil {
+				return err
+			}
+			if reservation == nil || reservation.Status != types.ReservationStatusPending || !isHoldExpired(*reservation, now) {
+				return nil
+			}
+
+			err = rooms.SaveSeats(reservation.PerformanceID, reservation.Seats, types.SeatStatusFree)
+			if err != nil {
+				return fmt.Errorf("save seats: %w", err)
+			}
+			err = reservation.Transition(types.ReservationStatusAborted, now, systemCustomerID)
+			if err != nil {
+				return err
+			}
+			releaseVoucher(reservation, now)
+			err = reservations.Update(*reservation)
+			if err != nil {
+				return fmt.Errorf("update reservation: %w", err)
+			}
+			released = true
+			// the reservation keeps its price, the points redeemed for it are given back
+			return restorePoints(ledger, *reservation, now)
+		})
+		if err != nil {
+			return releasedIDs, fmt.Errorf("release reservation #%d: %w", pending.ReservationID, err)
+		}
+		if released {
+			releasedIDs = append(releasedIDs, pending.ReservationID)
+		}
+	}
+
+	return releasedIDs, nil
+}
+
+// RunHoldSweeper calls ReleaseExpiredHolds every interval, until ctx is done.
+// Failures are reported to onError, when not nil, and the sweeper keeps running.
+func (t *TheaterService) RunHoldSweeper(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := t.ReleaseExpiredHolds()
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+func isHoldExpired(reservation types.Reservation, now time.Time) bool {
+	return !reservation.ExpiresAt.IsZero() && !now.Before(reservation.ExpiresAt)
+}
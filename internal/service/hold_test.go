@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/clock"
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// TestConcurrentReservationsDoNotDoubleBook is meant to be run with the race detector: go test -race
+func TestConcurrentReservationsDoNotDoubleBook(t *testing.T) {
+	const reservationsCount = 300
+
+	rooms := dao.NewTheaterRoomsDAO()
+	service := NewTheaterService(dao.NewReservationDAO(), rooms, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false)
+	// no VIP quota applies to a performance without nature
+	performance := types.Performance{
+		ID:        1,
+		Play:      "The CICD by Corneille",
+		StartTime: time.Date(2023, time.April, 22, 21, 0, 0, 0, time.UTC),
+	}
+
+	results := make([]types.ReservationResult, reservationsCount)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			category := types.ZoneCategoryStandard
+			if i%5 == 0 {
+				category = types.ZoneCategoryPremium
+			}
+			results[i] = service.Reserve(types.ReservationRequest{
+				CustomerID:       int64(i),
+				ReservationCount: 1 + i%3,
+				Category:         category,
+				Performance:      performance,
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	heldBy := make(map[string]int64)
+	for _, result := range results {
+		for _, seat := range result.Seats {
+			if reservationID, ok := heldBy[seat]; ok {
+				t.Errorf("Seat %s given to reservations #%d and #%d", seat, reservationID, result.Reservation.ReservationID)
+			}
+			heldBy[seat] = result.Reservation.ReservationID
+		}
+	}
+	if len(heldBy) == 0 {
+		t.Fatal("No seat reserved")
+	}
+
+	room, err := rooms.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	pendingSeats := 0
+	for _, zone := range room.Zones {
+		for _, row := range zone.Rows {
+			for _, seat := range row.Seats {
+				if seat.Status == types.SeatStatusBookingPending {
+					pendingSeats++
+					if _, ok := heldBy[seat.SeatID]; !ok {
+						t.Errorf("Seat %s is pending but was given to no reservation", seat.SeatID)
+					}
+				}
+			}
+		}
+	}
+	if pendingSeats != len(heldBy) {
+		t.Errorf("Expected %d pending seats, got %d", len(heldBy), pendingSeats)
+	}
+}
+
+func TestReleaseExpiredHolds(t *testing.T) {
+	now := time.Date(2023, time.April, 1, 10, 0, 0, 0, time.UTC)
+	rooms := dao.NewTheaterRoomsDAO()
+	reservations := dao.NewReservationDAO()
+	service := NewTheaterService(reservations, rooms, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false,
+		WithClock(clock.Func(func() time.Time { return now })),
+		WithHoldDuration(10*time.Minute),
+	)
+	performance := types.Performance{
+		ID:        1,
+		Play:      "The CICD by Corneille",
+		StartTime: time.Date(2023, time.April, 22, 21, 0, 0, 0, time.UTC),
+	}
+
+	result := service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance})
+	if result.Err != nil {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	reservationID := result.Reservation.ReservationID
+
+	now = now.Add(9 * time.Minute)
+	released, err := service.ReleaseExpiredHolds()
+	if err != nil || len(released) > 0 {
+		t.Fatalf("Expected no release before expiry, got %v (%v)", released, err)
+	}
+
+	now = now.Add(time.Minute)
+	released, err = service.ReleaseExpiredHolds()
+	if err != nil {
+		t.Fatalf("Failed to release holds: %v", err)
+	}
+	if !slices.Equal(released, []int64{reservationID}) {
+		t.Errorf("Expected reservation #%d to be released, got %v", reservationID, released)
+	}
+
+	reservation, err := reservations.Find(reservationID)
+	if err != nil || reservation == nil {
+		t.Fatalf("Failed to find reservation: %v", err)
+	}
+	if reservation.Status != types.ReservationStatusAborted {
+		t.Errorf("Expected reservation to be %s, got %s", types.ReservationStatusAborted, reservation.Status)
+	}
+	room, err := rooms.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	for _, seat := range room.Zones[0].Rows[0].Seats {
+		if slices.Contains(result.Seats, seat.SeatID) && seat.Status != types.SeatStatusFree {
+			t.Errorf("Expected seat %s to be released, got %s", seat.SeatID, seat.Status)
+		}
+	}
+}
+
+func TestConfirmReservation(t *testing.T) {
+	now := time.Date(2023, time.April, 1, 10, 0, 0, 0, time.UTC)
+	rooms := dao.NewTheaterRoomsDAO()
+	reservations := dao.NewReservationDAO()
+	service := NewTheaterService(reservations, rooms, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false,
+		WithClock(clock.Func(func() time.Time { return now })),
+		WithHoldDuration(10*time.Minute),
+	)
+	performance := types.Performance{
+		ID:        1,
+		Play:      "The CICD by Corneille",
+		StartTime: time.Date(2023, time.April, 22, 21, 0, 0, 0, time.UTC),
+	}
+	reserve := func() int64 {
+		result := service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance})
+		if result.Err != nil {
+			t.Fatalf("Failed to reserve: %v", result.Err)
+		}
+		return result.Reservation.ReservationID
+	}
+
+	confirmedID := reserve()
+	now = now.Add(5 * time.Minute)
+	err := service.ConfirmReservation(1, confirmedID)
+	if err != nil {
+		t.Fatalf("Failed to confirm reservation: %v", err)
+	}
+	reservation, err := reservations.Find(confirmedID)
+	if err != nil || reservation == nil {
+		t.Fatalf("Failed to find reservation: %v", err)
+	}
+	if reservation.Status != types.ReservationStatusConfirmed || !reservation.ConfirmedAt.Equal(now) {
+		t.Errorf("Unexpected confirmed reservation: %+v", reservation)
+	}
+	room, err := rooms.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	for _, seat := range room.Zones[0].Rows[0].Seats {
+		if slices.Contains(reservation.Seats, seat.SeatID) && seat.Status != types.SeatStatusBooked {
+			t.Errorf("Expected seat %s to be booked, got %s", seat.SeatID, seat.Status)
+		}
+	}
+
+	history, err := service.ReservationHistory(confirmedID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 2 || history[0].To != types.ReservationStatusPending || history[1].From != types.ReservationStatusPending ||
+		history[1].To != types.ReservationStatusConfirmed || !history[1].At.Equal(now) || history[1].CustomerID != 1 {
+		t.Errorf("Unexpected history: %+v", history)
+	}
+
+	err = service.ConfirmReservation(1, confirmedID)
+	if !errors.Is(err, ErrReservationAlreadyConfirmed) {
+		t.Errorf("Expected ErrReservationAlreadyConfirmed, got %v", err)
+	}
+
+	expiredID := reserve()
+	now = now.Add(10 * time.Minute)
+	err = service.ConfirmReservation(1, expiredID)
+	if !errors.Is(err, ErrReservationExpired) {
+		t.Errorf("Expected ErrReservationExpired, got %v", err)
+	}
+	_, err = service.ReleaseExpiredHolds()
+	if err != nil {
+		t.Fatalf("Failed to release holds: %v", err)
+	}
+	err = service.ConfirmReservation(1, expiredID)
+	if !errors.Is(err, types.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition once aborted, got %v", err)
+	}
+
+	err = service.ConfirmReservation(1, 1)
+	if !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("Expected ErrReservationNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// LoyaltyBalance returns the loyalty points the customer can redeem
+func (t *TheaterService) LoyaltyBalance(customerID int64) (int64, error) {
+	entries, err := t.loyaltyLedger.FetchLoyaltyEntries(customerID)
+	if err != nil {
+		return 0, fmt.Errorf("fetch loyalty entries: %w", err)
+	}
+	return types.LoyaltyBalance(entries), nil
+}
+
+// checkLoyaltyBalance returns dao.ErrInsufficientPoints when the customer cannot redeem the given points
+func (t *TheaterService) checkLoyaltyBalance(customerID int64, points int64) error {
+	if points == 0 {
+		return nil
+	}
+	balance, err := t.LoyaltyBalance(customerID)
+	if err != nil {
+		return err
+	}
+	if balance < points {
+		return fmt.Errorf("%w: %d points requested, %d available", dao.ErrInsufficientPoints, points, balance)
+	}
+	return nil
+}
+
+// redeemPoints debits the points redeemed by a new reservation
+func redeemPoints(ledger dao.LoyaltyLedgerRepository, reservation types.Reservation, at time.Time) error {
+	return appendLoyaltyEntry(ledger, reservation, types.LoyaltyEntryRedeem, -reservation.Price.RedeemedPoints, at)
+}
+
+// restorePoints credits back the points redeemed by a reservation whose hold expired
+func restorePoints(ledger dao.LoyaltyLedgerRepository, reservation types.Reservation, at time.Time) error {
+	return appendLoyaltyEntry(ledger, reservation, types.LoyaltyEntryRestore, reservation.Price.RedeemedPoints, at)
+}
+
+// earnPoints credits the points earned by a confirmed reservation
+func earnPoints(ledger dao.LoyaltyLedgerRepository, reservation types.Reservation, at time.Time) error {
+	return appendLoyaltyEntry(ledger, reservation, types.LoyaltyEntryEarn, reservation.EarnedPoints, at)
+}
+
+// settlePoints gives back the points redeemed for seats which have been released, and takes back the points earned with them,
+// given the state of the reservation before and after seats were released
+func settlePoints(ledger dao.LoyaltyLedgerRepository, former types.Reservation, reservation types.Reservation, at time.Time) error {
+	err := appendLoyaltyEntry(ledger, reservation, types.LoyaltyEntryRestore, former.Price.RedeemedPoints-reservation.Price.RedeemedPoints, at)
+	if err != nil {
+		return err
+	}
+	return appendLoyaltyEntry(ledger, reservation, types.LoyaltyEntryRevoke, reservation.EarnedPoints-former.EarnedPoints, at)
+}
+
+// keptEarnedPoints returns the points still earned by a reservation once some of its seats have been released
+func keptEarnedPoints(reservation types.Reservation) int64 {
+	return min(reservation.EarnedPoints, types.EarnedPoints(reservation.Price.TotalAmountDue))
+}
+
+func appendLoyaltyEntry(ledger dao.LoyaltyLedgerRepository, reservation types.Reservation, kind types.LoyaltyEntryKind, points int64, at time.Time) error {
+	if points == 0 {
+		return nil
+	}
+	err := ledger.AppendLoyaltyEntry(types.LoyaltyEntry{
+		CustomerID:    reservation.CustomerID,
+		ReservationID: reservation.ReservationID,
+		Kind:          kind,
+		Points:        points,
+		At:            at,
+	})
+	if err != nil {
+		return fmt.Errorf("%s loyalty points: %w", kind, err)
+	}
+	return nil
+}
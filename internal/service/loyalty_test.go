@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/clock"
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestSubscriptionTiers(t *testing.T) {
+	engine := NewPricingEngine(dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO())
+	engine.clock = bookingClock
+
+	tests := []struct {
+		customerID     int64
+		tier           types.SubscriptionTier
+		totalAmountDue string
+	}{
+		{2, types.SubscriptionTierNone, "112.00"},
+		{4, types.SubscriptionTierBasic, "100.80"},
+		{1, types.SubscriptionTierGold, "92.40"},
+		{5, types.SubscriptionTierPatron, "84.00"},
+	}
+	for _, test := range tests {
+		request := types.ReservationRequest{CustomerID: test.customerID, Performance: performanceCICD}
+		price, err := engine.Quote(request, types.TheaterRoom{}, types.ZoneCategoryStandard, types.ZoneCategoryStandard, types.ZoneCategoryStandard, types.ZoneCategoryStandard)
+		if err != nil {
+			t.Fatalf("Failed to quote: %v", err)
+		}
+		if price.SubscriptionTier != test.tier || price.TotalAmountDue != money.MustParse(test.totalAmountDue, money.EUR) {
+			t.Errorf("Expected customer #%d to pay %s as %q subscriber, got %v as %q", test.customerID, test.totalAmountDue, test.tier, price.TotalAmountDue, price.SubscriptionTier)
+		}
+		for _, line := range price.Lines {
+			if line.Kind == types.PriceLineSubscriberDiscount && line.Rule != string(test.tier) {
+				t.Errorf("Expected subscriber discount line to name tier %s, got %q", test.tier, line.Rule)
+			}
+		}
+	}
+}
+
+func TestPriorityBookingWindow(t *testing.T) {
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime,
+		SaleStartsAt: bookingClock.Now().Add(24 * time.Hour)}
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+	// the sale opens in a day: gold and patron subscribers may already book, not the others
+	for customerID, open := range map[int64]bool{1: true, 2: false, 4: false, 5: true} {
+		result := service.Reserve(types.ReservationRequest{CustomerID: customerID, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performance})
+		if open && result.Err != nil {
+			t.Errorf("Expected customer #%d to book during the priority window, got %v", customerID, result.Err)
+		}
+		if !open && !errors.Is(result.Err, ErrSaleNotOpen) {
+			t.Errorf("Expected ErrSaleNotOpen for customer #%d, got %v", customerID, result.Err)
+		}
+	}
+}
+
+func TestVIPQuotaAccess(t *testing.T) {
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performanceScala}
+
+	result := service.Reserve(request)
+	if !errors.Is(result.Err, ErrNotEnoughVIPSeats) {
+		t.Fatalf("Expected ErrNotEnoughVIPSeats, got %v", result.Err)
+	}
+	// patrons may book the seats kept for VIPs
+	request.CustomerID = 5
+	result = service.Reserve(request)
+	if result.Err != nil || result.Status != types.ReservationStatusFulfillable {
+		t.Errorf("Expected patron to book VIP seats, got %s (%v)", result.Status, result.Err)
+	}
+}
+
+func TestLoyaltyPoints(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	now := bookingClock.Now()
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false,
+		WithClock(clock.Func(func() time.Time { return now })),
+	)
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance}
+
+	// points are earned once the reservation is confirmed: one per euro paid
+	earning := service.Reserve(request)
+	if earning.Err != nil {
+		t.Fatalf("Failed to reserve: %v", earning.Err)
+	}
+	expectBalance(t, service, 2, 0)
+	err := service.ConfirmReservation(2, earning.Reservation.ReservationID)
+	if err != nil {
+		t.Fatalf("Failed to confirm: %v", err)
+	}
+	expectBalance(t, service, 2, 56)
+
+	// points cannot be redeemed beyond the balance, and no seat is held then
+	request.RedeemPoints = 100
+	result := service.Reserve(request)
+	if !errors.Is(result.Err, dao.ErrInsufficientPoints) || result.Status != types.ReservationStatusAborted {
+		t.Errorf("Expected ErrInsufficientPoints, got %v", result.Err)
+	}
+
+	// 100 points are worth 1.00€
+	request.RedeemPoints = 50
+	redeeming := service.Reserve(request)
+	if redeeming.Err != nil {
+		t.Fatalf("Failed to reserve: %v", redeeming.Err)
+	}
+	lastLine := redeeming.Price.Lines[len(redeeming.Price.Lines)-1]
+	if redeeming.Price.TotalAmountDue != money.MustParse("55.50", money.EUR) || lastLine.Kind != types.PriceLinePointsRedemption || lastLine.Amount != money.MustParse("-0.50", money.EUR) {
+		t.Errorf("Expected a -0.50 points redemption line, got %v (%+v)", redeeming.Price.TotalAmountDue, lastLine)
+	}
+	expectBalance(t, service, 2, 6)
+
+	// redeemed points are given back when the hold expires
+	now = now.Add(DefaultHoldDuration)
+	_, err = service.ReleaseExpiredHolds()
+	if err != nil {
+		t.Fatalf("Failed to release expired holds: %v", err)
+	}
+	expectBalance(t, service, 2, 56)
+
+	// earned points are taken back with the seats given back
+	refund, err := service.CancelSeats(2, earning.Reservation.ReservationID, earning.Seats[:1])
+	if err != nil {
+		t.Fatalf("Failed to cancel seats: %v", err)
+	}
+	if refund != money.MustParse("28.00", money.EUR) {
+		t.Errorf("Expected refund of 28.00, got %v", refund)
+	}
+	expectBalance(t, service, 2, 28)
+
+	// redeemed points are given back when the reservation is cancelled
+	request.RedeemPoints = 28
+	request.ReservationCount = 1
+	redeeming = service.Reserve(request)
+	if redeeming.Err != nil {
+		t.Fatalf("Failed to reserve: %v", redeeming.Err)
+	}
+	expectBalance(t, service, 2, 0)
+	err = service.CancelReservation(2, redeeming.Reservation.ReservationID)
+	if err != nil {
+		t.Fatalf("Failed to cancel: %v", err)
+	}
+	expectBalance(t, service, 2, 28)
+
+	// points only pay for the amount due, the other ones are kept
+	quote, err := service.Quote(types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performance, RedeemPoints: 5000})
+	if err != nil {
+		t.Fatalf("Failed to quote: %v", err)
+	}
+	if !quote.TotalAmountDue.IsZero() || quote.RedeemedPoints != 2800 {
+		t.Errorf("Expected 2800 points to pay the whole amount, got %d points and %v due", quote.RedeemedPoints, quote.TotalAmountDue)
+	}
+}
+
+// failingLedger refuses to debit redeemed points
+type failingLedger struct {
+	*dao.LoyaltyLedgerDAO
+}
+
+func (l failingLedger) AppendLoyaltyEntry(entry types.LoyaltyEntry) error {
+	if entry.Kind == types.LoyaltyEntryRedeem {
+		return errors.New("ledger down")
+	}
+	return l.LoyaltyLedgerDAO.AppendLoyaltyEntry(entry)
+}
+
+// failingRooms refuses to hold seats
+type failingRooms struct {
+	*dao.TheaterRoomsDAO
+}
+
+func (failingRooms) HoldSeats(int64, []string) error {
+	return errors.New("rooms down")
+}
+
+func TestRedeemPointsFailure(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performance, RedeemPoints: 50}
+	ledger := dao.NewLoyaltyLedgerDAO()
+	err := ledger.AppendLoyaltyEntry(types.LoyaltyEntry{CustomerID: 2, Kind: types.LoyaltyEntryEarn, Points: 100, At: bookingClock.Now()})
+	if err != nil {
+		t.Fatalf("Failed to credit points: %v", err)
+	}
+
+	// no seat is held and no reservation is saved when the points cannot be debited
+	reservationDAO, roomsDAO := dao.NewReservationDAO(), dao.NewTheaterRoomsDAO()
+	service := NewTheaterService(reservationDAO, roomsDAO, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock),
+		WithLoyaltyLedger(failingLedger{ledger}))
+	room, err := roomsDAO.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	fillRatio := room.FillRatio()
+	result := service.Reserve(request)
+	if result.Err == nil || result.Status != types.ReservationStatusAborted {
+		t.Fatalf("Expected the reservation to be aborted, got %s (%v)", result.Status, result.Err)
+	}
+	room, err = roomsDAO.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	if room.FillRatio().Cmp(fillRatio) != 0 {
+		t.Errorf("Expected fill ratio to stay %v, got %v", fillRatio, room.FillRatio())
+	}
+	if reservation, _ := reservationDAO.Find(result.Reservation.ReservationID); reservation != nil {
+		t.Errorf("Expected no reservation to be saved, got %+v", reservation)
+	}
+
+	// the points debited are rolled back when the seats cannot be held
+	service = NewTheaterService(dao.NewReservationDAO(), failingRooms{dao.NewTheaterRoomsDAO()}, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock),
+		WithLoyaltyLedger(ledger))
+	result = service.Reserve(request)
+	if result.Err == nil || result.Status != types.ReservationStatusAborted {
+		t.Fatalf("Expected the reservation to be aborted, got %s (%v)", result.Status, result.Err)
+	}
+	expectBalance(t, service, 2, 100)
+}
+
+func expectBalance(t *testing.T, service TheaterService, customerID int64, expected int64) {
+	t.Helper()
+
+	balance, err := service.LoyaltyBalance(customerID)
+	if err != nil {
+		t.Fatalf("Failed to fetch loyalty balance: %v", err)
+	}
+	if balance != expected {
+		t.Errorf("Expected customer #%d to have %d points, got %d", customerID, expected, balance)
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/clock"
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// DefaultRoundingMode rounds amounts to the nearest cent, halves away from zero
+const DefaultRoundingMode = money.HalfUp
+
+var (
+	ErrInvalidTicketTypes = errors.New("there must be one ticket type per seat")
+	ErrTicketTypeNotSold  = errors.New("ticket type is not sold for the performance in this category")
+)
+
+// PricingEngine prices seats for a customer, for reservations as well as for quotes
+type PricingEngine struct {
+	performancePriceDAO     dao.PerformancePriceRepository
+	customerSubscriptionDAO dao.CustomerSubscriptionRepository
+	voucherDAO              dao.VoucherRepository
+	discountRules           []DiscountRule
+	discountCap             *big.Rat
+	clock                   clock.Clock
+	roundingMode            money.RoundingMode
+	conversionTable         *money.ConversionTable
+}
+
+func NewPricingEngine(performancePriceDAO dao.PerformancePriceRepository, voucherProgramDAO dao.VoucherProgramRepository, customerSubscriptionDAO dao.CustomerSubscriptionRepository) *PricingEngine {
+	return &PricingEngine{
+		performancePriceDAO:     performancePriceDAO,
+		customerSubscriptionDAO: customerSubscriptionDAO,
+		voucherDAO:              dao.NewVoucherDAO(),
+		discountRules:           defaultDiscountRules(voucherProgramDAO),
+		clock:                   clock.System{},
+		roundingMode:            DefaultRoundingMode,
+	}
+}
+
+// PricedSeat is a seat to price, its ID may be empty for quotes
+type PricedSeat struct {
+	SeatID     string
+	Category   types.ZoneCategory
+	TicketType types.TicketType
+}
+
+// Rules returns the pricing rules applying to the reservation request, as a price breakdown without seats.
+// Seats are priced in the requested currency, or in the currency of the performance price when it is empty.
+// The room is the current state of the performance room, for demand pricing.
+// Usage caps of the voucher code are not checked, they depend on the other reservations.
+func (e *PricingEngine) Rules(request types.ReservationRequest, room types.TheaterRoom) (types.PriceBreakdown, error) {
+	customerID, performance, currency := request.CustomerID, request.Performance, request.Currency
+	bookedAt := e.clock.Now()
+
+	performancePrice, err := e.performancePriceDAO.FetchPerformancePrice(performance.ID)
+	if err != nil {
+		return types.PriceBreakdown{}, fmt.Errorf("fetch performance price: %w", err)
+	}
+	categories := requestCategories(request, room)
+	err = checkTicketTypes(request, categories, performancePrice)
+	if err != nil {
+		return types.PriceBreakdown{}, err
+	}
+	seatPrice := performancePrice.SeatPrice
+	if currency != "" && currency != seatPrice.Currency {
+		if e.conversionTable == nil {
+			return types.PriceBreakdown{}, fmt.Errorf("%w from %s to %s: no conversion table", money.ErrNoConversionRate, seatPrice.Currency, currency)
+		}
+		seatPrice, err = e.conversionTable.Convert(seatPrice, currency, e.roundingMode)
+		if err != nil {
+			return types.PriceBreakdown{}, fmt.Errorf("convert performance price: %w", err)
+		}
+	}
+
+	tier, err := e.customerSubscriptionDAO.FetchCustomerSubscription(customerID)
+	if err != nil {
+		return types.PriceBreakdown{}, fmt.Errorf("fetch customer subscription: %w", err)
+	}
+
+	rules := types.PriceBreakdown{
+		SeatPrice:        seatPrice,
+		CategoryRatios:   performancePrice.CategoryRatios,
+		TicketTypeRatios: performancePrice.TicketTypeRatios,
+		SubscriptionTier: tier,
+		DiscountCap:      e.discountCap,
+		RedeemedPoints:   request.RedeemPoints,
+		RoundingMode:     e.roundingMode,
+	}
+
+	// check and apply discounts and fidelity program
+	ctx := DiscountContext{Request: request, PerformancePrice: performancePrice, SubscriptionTier: tier, BookedAt: bookedAt}
+	for _, rule := range e.discountRules {
+		discount, err := rule.Discount(ctx)
+		if err != nil {
+			return types.PriceBreakdown{}, err
+		}
+		if discount != nil {
+			rules.Discounts = append(rules.Discounts, *discount)
+		}
+	}
+	if request.VoucherCode != "" {
+		rules.Voucher, err = e.voucher(request, categories, seatPrice.Currency, bookedAt)
+		if err != nil {
+			return types.PriceBreakdown{}, err
+		}
+	}
+	if performancePrice.Demand != nil {
+		rules.DemandTiers = demandTiers(*performancePrice.Demand, room)
+	}
+	return rules, nil
+}
+
+// requestCategories returns the zone category of each seat to reserve: the one of the zone of the seats chosen by the customer,
+// the requested category otherwise. Chosen seats which are not in the room are left out.
+func requestCategories(request types.ReservationRequest, room types.TheaterRoom) []types.ZoneCategory {
+	if len(request.SeatIDs) == 0 {
+		categories := make([]types.ZoneCategory, request.ReservationCount)
+		for i := range categories {
+			categories[i] = request.Category
+		}
+		return categories
+	}
+	categories := make([]types.ZoneCategory, 0, len(request.SeatIDs))
+	for _, seatID := range request.SeatIDs {
+		if _, category, ok := room.FindSeat(seatID); ok {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+// checkTicketTypes returns an error when the ticket types of the request cannot be sold for the performance,
+// in the categories of the seats to reserve
+func checkTicketTypes(request types.ReservationRequest, categories []types.ZoneCategory, performancePrice types.PerformancePrice) error {
+	if len(request.TicketTypes) > 0 && len(request.TicketTypes) != request.ReservationCount {
+		return fmt.Errorf("%w: %d ticket types for %d seats", ErrInvalidTicketTypes, len(request.TicketTypes), request.ReservationCount)
+	}
+	for i, ticketType := range request.TicketTypes {
+		if i >= len(categories) {
+			break
+		}
+		if _, ok := performancePrice.TicketTypeRatios[ticketType][categories[i]]; !ok && ticketType != types.TicketTypeAdult {
+			return fmt.Errorf("%w: %s in %s", ErrTicketTypeNotSold, ticketType, categories[i])
+		}
+	}
+	return nil
+}
+
+// demandTiers returns the demand tier applying to each zone category, given the occupancy of the room
+func demandTiers(demand types.DemandPricing, room types.TheaterRoom) map[types.ZoneCategory]types.DemandTier {
+	tiers := make(map[types.ZoneCategory]types.DemandTier, len(types.ZoneCategories))
+	for _, category := range types.ZoneCategories {
+		fillRatio := room.FillRatio()
+		if demand.Scope == types.DemandScopeZone {
+			fillRatio = room.CategoryFillRatio(category)
+		}
+		tier, ok := demand.Tier(fillRatio)
+		if ok {
+			tiers[category] = tier
+		}
+	}
+	return tiers
+}
+
+// voucher returns the voucher of the request code, if it can be used for the request at the booking time,
+// for seats of the given categories
+func (e *PricingEngine) voucher(request types.ReservationRequest, categories []types.ZoneCategory, currency money.Currency, bookedAt time.Time) (*types.Voucher, error) {
+	voucher, err := e.voucherDAO.FetchVoucher(request.VoucherCode)
+	if err != nil {
+		return nil, fmt.Errorf("fetch voucher: %w", err)
+	}
+	if voucher == nil {
+		return nil, fmt.Errorf("%w: %s", ErrVoucherNotFound, request.VoucherCode)
+	}
+	if !voucher.IsValidAt(bookedAt) {
+		return nil, fmt.Errorf("%w: %s", ErrVoucherExpired, voucher.Code)
+	}
+	applies := voucher.Kind != types.VoucherKindFixed || voucher.Amount.Currency == currency
+	if len(request.SeatIDs) == 0 {
+		categories = []types.ZoneCategory{request.Category}
+	}
+	for _, category := range categories {
+		applies = applies && voucher.AppliesTo(request.Performance.ID, category)
+	}
+	if !applies {
+		return nil, fmt.Errorf("%w: %s", ErrVoucherNotApplicable, voucher.Code)
+	}
+	return voucher, nil
+}
+
+// Quote prices seats of the given categories for the request, without reserving them
+func (e *PricingEngine) Quote(request types.ReservationRequest, room types.TheaterRoom, categories ...types.ZoneCategory) (types.PriceBreakdown, error) {
+	rules, err := e.Rules(request, room)
+	if err != nil {
+		return types.PriceBreakdown{}, err
+	}
+
+	seats := make([]PricedSeat, 0, len(categories))
+	for i, category := range categories {
+		seats = append(seats, PricedSeat{Category: category, TicketType: request.TicketType(i)})
+	}
+	return PriceSeats(rules, seats), nil
+}
+
+// PriceSeats prices the given seats with the pricing rules of rules, and itemizes the bill.
+//
+// Discounts are computed on the exact price, which is only rounded to the minor unit once, before redeeming points:
+// each discount line is its exact amount rounded, and a rounding line holds the difference between the sum of the lines
+// and the rounded total, so that the lines add up to the total.
+func PriceSeats(rules types.PriceBreakdown, seats []PricedSeat) types.PriceBreakdown {
+	price := types.PriceBreakdown{
+		SeatPrice:        rules.SeatPrice,
+		CategoryRatios:   rules.CategoryRatios,
+		DemandTiers:      rules.DemandTiers,
+		TicketTypeRatios: rules.TicketTypeRatios,
+		SubscriptionTier: rules.SubscriptionTier,
+		Discounts:        rules.Discounts,
+		DiscountCap:      rules.DiscountCap,
+		Voucher:          rules.Voucher,
+		RoundingMode:     rules.RoundingMode,
+	}
+	currency := price.SeatPrice.Currency
+
+	initialPrice := money.New(0, currency)
+	for _, seat := range seats {
+		price.Lines = append(price.Lines, types.PriceLine{
+			Kind:   types.PriceLineBasePrice,
+			SeatID: seat.SeatID,
+			Amount: price.SeatPrice,
+		})
+
+		seatPrice := price.SeatPrice
+		if categoryRatio, ok := price.CategoryRatios[seat.Category]; ok {
+			seatPrice = seatPrice.MulRat(categoryRatio, price.RoundingMode)
+		}
+		if seatPrice != price.SeatPrice {
+			price.Lines = append(price.Lines, types.PriceLine{
+				Kind:   types.PriceLineCategorySurcharge,
+				SeatID: seat.SeatID,
+				Amount: seatPrice.Sub(price.SeatPrice),
+			})
+		}
+		if tier, ok := price.DemandTiers[seat.Category]; ok {
+			adjustedPrice := seatPrice.MulRat(tier.Multiplier, price.RoundingMode)
+			if adjustedPrice != seatPrice {
+				price.Lines = append(price.Lines, types.PriceLine{
+					Kind:   types.PriceLineDemandAdjustment,
+					SeatID: seat.SeatID,
+					Rule:   tier.Name,
+					Amount: adjustedPrice.Sub(seatPrice),
+				})
+			}
+			seatPrice = adjustedPrice
+		}
+		if ticketRatio, ok := price.TicketTypeRatios[seat.TicketType][seat.Category]; ok {
+			ticketPrice := seatPrice.MulRat(ticketRatio, price.RoundingMode)
+			if ticketPrice != seatPrice {
+				price.Lines = append(price.Lines, types.PriceLine{
+					Kind:   types.PriceLineTicketType,
+					SeatID: seat.SeatID,
+					Rule:   string(seat.TicketType),
+					Amount: ticketPrice.Sub(seatPrice),
+				})
+			}
+			seatPrice = ticketPrice
+		}
+		initialPrice = initialPrice.Add(seatPrice)
+	}
+	price.InitialPrice = initialPrice
+
+	exactTotal := initialPrice.Rat()
+	linesTotal := initialPrice
+	discountLine := func(kind types.PriceLineKind, rule string, exactBefore *big.Rat) {
+		amount := money.FromRat(new(big.Rat).Sub(exactTotal, exactBefore), currency, price.RoundingMode)
+		if amount.IsZero() {
+			return
+		}
+		price.Lines = append(price.Lines, types.PriceLine{Kind: kind, Rule: rule, Amount: amount})
+		linesTotal = linesTotal.Add(amount)
+	}
+	price.DiscountOutcomes = stackDiscounts(price.Discounts, price.DiscountCap)
+	for _, outcome := range price.DiscountOutcomes {
+		if outcome.Share.Sign() == 0 {
+			continue
+		}
+		exactBefore := new(big.Rat).Set(exactTotal)
+		exactTotal.Sub(exactTotal, new(big.Rat).Mul(initialPrice.Rat(), outcome.Share))
+		discountLine(outcome.Discount.Kind, outcome.Discount.Rule, exactBefore)
+	}
+	if voucher := price.Voucher; voucher != nil {
+		exactBefore := new(big.Rat).Set(exactTotal)
+		switch voucher.Kind {
+		case types.VoucherKindPercent:
+			exactTotal.Mul(exactTotal, new(big.Rat).Sub(big.NewRat(1, 1), voucher.Ratio))
+		case types.VoucherKindFixed:
+			exactTotal.Sub(exactTotal, voucher.Amount.Rat())
+			if exactTotal.Sign() < 0 {
+				exactTotal.SetInt64(0)
+			}
+		}
+		discountLine(types.PriceLineVoucherCode, voucher.Code, exactBefore)
+	}
+	totalBilling := money.FromRat(exactTotal, currency, price.RoundingMode)
+	if rounding := totalBilling.Sub(linesTotal); !rounding.IsZero() {
+		price.Lines = append(price.Lines, types.PriceLine{
+			Kind:   types.PriceLineRounding,
+			Amount: rounding,
+		})
+	}
+	if rules.RedeemedPoints > 0 && totalBilling.Sign() > 0 {
+		// redeem the points needed to pay the whole amount, at most
+		payablePoints := new(big.Rat).Quo(totalBilling.Rat(), types.PointValue)
+		maxPoints := new(big.Int).Quo(payablePoints.Num(), payablePoints.Denom()).Int64()
+		price.RedeemedPoints = min(rules.RedeemedPoints, maxPoints)
+		pointsValue := new(big.Rat).Mul(big.NewRat(price.RedeemedPoints, 1), types.PointValue)
+		redeemed := money.FromRat(pointsValue, currency, price.RoundingMode)
+		if !redeemed.IsZero() {
+			price.Lines = append(price.Lines, types.PriceLine{
+				Kind:   types.PriceLinePointsRedemption,
+				Amount: redeemed.Neg(),
+			})
+		}
+		totalBilling = totalBilling.Sub(redeemed)
+	}
+	price.TotalAmountDue = totalBilling
+
+	return price
+}
+
+// pricedSeats associates seats to their category and ticket type, adult when missing
+func pricedSeats(seats []string, seatsCategory map[string]types.ZoneCategory, seatsTicketType map[string]types.TicketType) []PricedSeat {
+	priced := make([]PricedSeat, 0, len(seats))
+	for _, seat := range seats {
+		ticketType, ok := seatsTicketType[seat]
+		if !ok {
+			ticketType = types.TicketTypeAdult
+		}
+		priced = append(priced, PricedSeat{SeatID: seat, Category: seatsCategory[seat], TicketType: ticketType})
+	}
+	return priced
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestQuote(t *testing.T) {
+	engine := NewPricingEngine(dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO())
+	engine.clock = bookingClock
+
+	request := types.ReservationRequest{CustomerID: 1, Performance: performanceCICD}
+	price, err := engine.Quote(request, types.TheaterRoom{}, types.ZoneCategoryStandard, types.ZoneCategoryPremium)
+	if err != nil {
+		t.Fatalf("Failed to quote: %v", err)
+	}
+
+	expected := []struct {
+		kind   types.PriceLineKind
+		amount string
+	}{
+		{types.PriceLineBasePrice, "35.00"},
+		{types.PriceLineBasePrice, "35.00"},
+		{types.PriceLineCategorySurcharge, "17.50"},
+		{types.PriceLineSubscriberDiscount, "-15.31"},
+		{types.PriceLineVoucherDiscount, "-14.44"},
+	}
+	if len(price.Lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %v", len(expected), price.Lines)
+	}
+	for i, line := range price.Lines {
+		if line.Kind != expected[i].kind || line.Amount != money.MustParse(expected[i].amount, money.EUR) {
+			t.Errorf("Expected line #%d to be %s %s, got %s %v", i, expected[i].kind, expected[i].amount, line.Kind, line.Amount)
+		}
+	}
+
+	if price.TotalAmountDue != money.MustParse("57.75", money.EUR) {
+		t.Errorf("Expected total amount due of 57.75 EUR, got %v", price.TotalAmountDue)
+	}
+}
+
+func TestQuoteInAnotherCurrency(t *testing.T) {
+	table, err := money.ParseConversionTable(strings.NewReader(`{"base": "EUR", "rates": {"USD": "1.08"}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse conversion table: %v", err)
+	}
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock), WithConversionTable(table))
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performanceCICD, Currency: money.USD}
+
+	// 35 EUR = 37.80 USD, voucher discount applied
+	price, err := service.Quote(request)
+	if err != nil {
+		t.Fatalf("Failed to quote: %v", err)
+	}
+	if price.SeatPrice != money.MustParse("37.80", money.USD) || price.TotalAmountDue != money.MustParse("30.24", money.USD) {
+		t.Errorf("Expected a 37.80 USD seat for 30.24 USD, got %v for %v", price.SeatPrice, price.TotalAmountDue)
+	}
+
+	request.Currency = money.CHF
+	_, err = service.Quote(request)
+	if !errors.Is(err, money.ErrNoConversionRate) {
+		t.Errorf("Expected ErrNoConversionRate, got %v", err)
+	}
+}
+
+func TestReserveWithoutConversionTable(t *testing.T) {
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+	result := service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performanceCICD, Currency: money.USD})
+	if result.Status != types.ReservationStatusAborted || !errors.Is(result.Err, money.ErrNoConversionRate) {
+		t.Errorf("Expected reservation to be aborted with ErrNoConversionRate, got %s: %v", result.Status, result.Err)
+	}
+}
+
+func TestPriceSeatsRoundingLine(t *testing.T) {
+	rules := types.PriceBreakdown{
+		SeatPrice: money.MustParse("35.00", money.EUR),
+		Discounts: []types.Discount{
+			{Kind: types.PriceLineSubscriberDiscount, Ratio: big.NewRat(175, 1000), Priority: 20, Stacking: types.StackingMultiply},
+		},
+		RoundingMode: money.HalfUp,
+	}
+
+	// the exact discount is 6.125, the exact total 28.875: both are rounded up, one cent is given back
+	price := PriceSeats(rules, []PricedSeat{{Category: types.ZoneCategoryStandard}})
+	expected := []types.PriceLine{
+		{Kind: types.PriceLineBasePrice, Amount: money.MustParse("35.00", money.EUR)},
+		{Kind: types.PriceLineSubscriberDiscount, Amount: money.MustParse("-6.13", money.EUR)},
+		{Kind: types.PriceLineRounding, Amount: money.MustParse("0.01", money.EUR)},
+	}
+	if !slices.Equal(price.Lines, expected) {
+		t.Errorf("Expected lines %v, got %v", expected, price.Lines)
+	}
+	if price.TotalAmountDue != money.MustParse("28.88", money.EUR) {
+		t.Errorf("Expected total amount due of 28.88 EUR, got %v", price.TotalAmountDue)
+	}
+}
+
+func TestPriceSeatsLinesAddUpToTotal(t *testing.T) {
+	categories := []types.ZoneCategory{types.ZoneCategoryStandard, types.ZoneCategoryPremium}
+	modes := []money.RoundingMode{money.HalfUp, money.HalfEven, money.HalfDown, money.Down, money.Up}
+	stackings := []types.StackingMode{types.StackingMultiply, types.StackingAdditive, types.StackingBestOnly, types.StackingExclusive}
+
+	property := func(seatPrice uint16, seatCategories []bool, subscriberPermille, voucherPermille, capPermille uint16, stackingIndex, modeIndex uint8) bool {
+		stacking := stackings[int(stackingIndex)%len(stackings)]
+		rules := types.PriceBreakdown{
+			SeatPrice: money.New(int64(seatPrice), money.EUR),
+			CategoryRatios: map[types.ZoneCategory]*big.Rat{
+				types.ZoneCategoryPremium: big.NewRat(3, 2),
+			},
+			Discounts: []types.Discount{
+				{Kind: types.PriceLineSubscriberDiscount, Ratio: big.NewRat(int64(subscriberPermille%1000), 1000), Priority: 20, Stacking: stacking},
+				{Kind: types.PriceLineVoucherDiscount, Ratio: big.NewRat(int64(voucherPermille%1000), 1000), Priority: 10, Stacking: stacking},
+			},
+			DiscountCap:  big.NewRat(int64(capPermille%1001), 1000),
+			RoundingMode: modes[int(modeIndex)%len(modes)],
+		}
+		seats := make([]PricedSeat, 0, len(seatCategories))
+		for _, premium := range seatCategories {
+			category := categories[0]
+			if premium {
+				category = categories[1]
+			}
+			seats = append(seats, PricedSeat{Category: category})
+		}
+
+		price := PriceSeats(rules, seats)
+		total := money.New(0, money.EUR)
+		for _, line := range price.Lines {
+			total = total.Add(line.Amount)
+		}
+		return total == price.TotalAmountDue && price.TotalAmountDue.Sign() >= 0
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// demandPriceDAO prices all performances 35.00 EUR, 52.50 EUR in premium, with demand pricing
+type demandPriceDAO struct {
+	demand types.DemandPricing
+}
+
+func (d demandPriceDAO) FetchPerformancePrice(int64) (types.PerformancePrice, error) {
+	return types.PerformancePrice{
+		SeatPrice:      money.MustParse("35.00", money.EUR),
+		CategoryRatios: map[types.ZoneCategory]*big.Rat{types.ZoneCategoryPremium: big.NewRat(3, 2)},
+		Demand:         &d.demand,
+	}, nil
+}
+
+// demandRoom has half of its standard seats booked, and all of its premium seats free
+var demandRoom = types.TheaterRoom{
+	Zones: []types.Zone{
+		{Category: types.ZoneCategoryStandard, Rows: []types.Row{{Seats: []types.Seat{
+			{SeatID: "A1", Status: types.SeatStatusBooked}, {SeatID: "A2", Status: types.SeatStatusBooked},
+			{SeatID: "A3", Status: types.SeatStatusFree}, {SeatID: "A4", Status: types.SeatStatusFree},
+		}}}},
+		{Category: types.ZoneCategoryPremium, Rows: []types.Row{{Seats: []types.Seat{
+			{SeatID: "P1", Status: types.SeatStatusFree}, {SeatID: "P2", Status: types.SeatStatusFree},
+			{SeatID: "P3", Status: types.SeatStatusFree}, {SeatID: "P4", Status: types.SeatStatusFree},
+		}}}},
+	},
+}
+
+func newDemandPricing(scope types.DemandScope) types.DemandPricing {
+	return types.DemandPricing{
+		Scope: scope,
+		Tiers: []types.DemandTier{
+			{Name: "LOW", MinFillRatio: big.NewRat(0, 1), Multiplier: big.NewRat(9, 10)},
+			{Name: "HIGH", MinFillRatio: big.NewRat(1, 2), Multiplier: big.NewRat(6, 5)},
+		},
+	}
+}
+
+func TestDemandPricing(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas in such a small room
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+
+	tests := []struct {
+		scope          types.DemandScope
+		category       types.ZoneCategory
+		expectedTier   string
+		expectedAmount string
+		expectedTotal  string
+	}{
+		// a quarter of the room is taken
+		{types.DemandScopeRoom, types.ZoneCategoryStandard, "LOW", "-3.50", "25.20"},
+		{types.DemandScopeRoom, types.ZoneCategoryPremium, "LOW", "-5.25", "37.80"},
+		// half of the standard zone is taken, none of the premium one
+		{types.DemandScopeZone, types.ZoneCategoryStandard, "HIGH", "7.00", "33.60"},
+		{types.DemandScopeZone, types.ZoneCategoryPremium, "LOW", "-5.25", "37.80"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.scope)+" "+string(test.category), func(t *testing.T) {
+			rooms := dao.NewTheaterRoomsDAO()
+			_ = rooms.SaveTheaterRoom(performance.ID, demandRoom)
+			service := NewTheaterService(dao.NewReservationDAO(), rooms, demandPriceDAO{newDemandPricing(test.scope)}, dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+			price, err := service.Quote(types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: test.category, Performance: performance})
+			if err != nil {
+				t.Fatalf("Failed to quote: %v", err)
+			}
+			var demandLine *types.PriceLine
+			for i, line := range price.Lines {
+				if line.Kind == types.PriceLineDemandAdjustment {
+					demandLine = &price.Lines[i]
+				}
+			}
+			if demandLine == nil || demandLine.Rule != test.expectedTier || demandLine.Amount != money.MustParse(test.expectedAmount, money.EUR) {
+				t.Errorf("Expected a %s demand adjustment of %s, got %v", test.expectedTier, test.expectedAmount, price.Lines)
+			}
+			if price.TotalAmountDue != money.MustParse(test.expectedTotal, money.EUR) {
+				t.Errorf("Expected total amount due of %s, got %v", test.expectedTotal, price.TotalAmountDue)
+			}
+		})
+	}
+}
+
+func TestDemandPricingRefund(t *testing.T) {
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	rooms := dao.NewTheaterRoomsDAO()
+	_ = rooms.SaveTheaterRoom(performance.ID, demandRoom)
+	service := NewTheaterService(dao.NewReservationDAO(), rooms, demandPriceDAO{newDemandPricing(types.DemandScopeZone)}, dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+	result := service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance})
+	if result.Err != nil {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	if result.Price.TotalAmountDue != money.MustParse("67.20", money.EUR) {
+		t.Errorf("Expected two seats at the HIGH tier for 67.20, got %v", result.Price.TotalAmountDue)
+	}
+
+	// the zone is full now, but seats are refunded at the tier they were booked at
+	refund, err := service.CancelSeats(2, result.Reservation.ReservationID, []string{result.Seats[0]})
+	if err != nil {
+		t.Fatalf("Failed to cancel seat: %v", err)
+	}
+	if refund != money.MustParse("33.60", money.EUR) {
+		t.Errorf("Expected a 33.60 refund, got %v", refund)
+	}
+}
@@ -1,47 +1,106 @@
 package service
 
 import (
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/benoitmasson/theater-reservation-kata/internal/clock"
 	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
 	"github.com/benoitmasson/theater-reservation-kata/internal/types"
 )
 
 type ReservationService struct {
-	currentID int64
-	idMutex   sync.Mutex
+	currentID     int64
+	currentIDRead bool
+	idMutex       *sync.Mutex
 
-	reservationDAO dao.ReservationDAO
+	reservationDAO dao.ReservationRepository
+	clock          clock.Clock
 }
 
-func NewReservationService(reservationDAO dao.ReservationDAO) ReservationService {
+func NewReservationService(reservationDAO dao.ReservationRepository) ReservationService {
 	return ReservationService{
 		currentID:      123455,
+		idMutex:        &sync.Mutex{},
 		reservationDAO: reservationDAO,
+		clock:          clock.System{},
 	}
 }
 
-func (r *ReservationService) InitNewReservation() int64 {
+func (r *ReservationService) InitNewReservation() (int64, error) {
 	r.idMutex.Lock()
 	defer r.idMutex.Unlock()
 
+	if !r.currentIDRead {
+		// do not reuse the IDs of reservations stored by a previous run
+		lastID, err := r.reservationDAO.LastReservationID()
+		if err != nil {
+			return 0, err
+		}
+		r.currentID = max(r.currentID, lastID)
+		r.currentIDRead = true
+	}
+
 	r.currentID++
-	return r.currentID
+	return r.currentID, nil
 }
 
-func (r *ReservationService) Update(reservation types.Reservation) {
-	r.reservationDAO.Update(reservation)
+func (r *ReservationService) Update(reservation types.Reservation) error {
+	return r.reservationDAO.Update(reservation)
 }
 
-func (r *ReservationService) Find(reservationID int64) *types.Reservation {
+func (r *ReservationService) Find(reservationID int64) (*types.Reservation, error) {
 	return r.reservationDAO.Find(reservationID)
 }
 
-func (r *ReservationService) Cancel(reservationID int64) {
-	reservation := r.Find(reservationID)
-	if reservation != nil {
-		reservation.Status = types.ReservationStatusCancelled
-		reservation.Seats = []string{}
-		r.Update(*reservation)
+func (r *ReservationService) FindByStatus(status types.ReservationStatus) ([]types.Reservation, error) {
+	return r.reservationDAO.FindByStatus(status)
+}
+
+// cancelReservation cancels a reservation on behalf of the given customer, its seats are moved to the released ones.
+// It returns the reservation as it was before being cancelled, whose seats have just been released, and the cancelled reservation;
+// the caller is in charge of freeing the seats in the theater room.
+func cancelReservation(reservations dao.ReservationRepository, reservationID int64, customerID int64, at time.Time) (types.Reservation, *types.Reservation, error) {
+	reservation, err := reservations.Find(reservationID)
+	if err != nil {
+		return types.Reservation{}, nil, fmt.Errorf("find reservation: %w", err)
+	}
+	if reservation == nil {
+		return types.Reservation{}, nil, fmt.Errorf("%w: #%d", ErrReservationNotFound, reservationID)
+	}
+	if reservation.Status == types.ReservationStatusCancelled {
+		return types.Reservation{}, nil, fmt.Errorf("%w: #%d", ErrReservationAlreadyCancelled, reservationID)
+	}
+
+	former := *reservation
+	err = reservation.Transition(types.ReservationStatusCancelled, at, customerID)
+	if err != nil {
+		return types.Reservation{}, nil, err
+	}
+	releaseVoucher(reservation, at)
+	releasedSeats := reservation.Seats
+	reservation.ReleasedSeats = append(slices.Clip(reservation.ReleasedSeats), releasedSeats...)
+	reservation.Seats = []string{}
+	reservation.RefundedAmount = reservation.RefundedAmount.Add(reservation.Price.TotalAmountDue)
+	reservation.Price = PriceSeats(reservation.Price, nil)
+	reservation.EarnedPoints = 0
+	err = reservations.Update(*reservation)
+	if err != nil {
+		return types.Reservation{}, nil, fmt.Errorf("update reservation: %w", err)
+	}
+	return former, reservation, nil
+}
+
+// History returns the status transitions of a reservation, oldest first
+func (r *ReservationService) History(reservationID int64) ([]types.ReservationTransition, error) {
+	reservation, err := r.Find(reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil {
+		return nil, fmt.Errorf("%w: #%d", ErrReservationNotFound, reservationID)
 	}
+	return reservation.History, nil
 }
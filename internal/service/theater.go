@@ -1,202 +1,578 @@
 package service
 
 import (
+	"errors"
 	"fmt"
-	"math"
 	"math/big"
 	"slices"
-	"strconv"
 	"strings"
+	"time"
 
+	"github.com/benoitmasson/theater-reservation-kata/internal/clock"
 	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/encoder"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
 	"github.com/benoitmasson/theater-reservation-kata/internal/types"
 )
 
 type TheaterService struct {
 	reservationService ReservationService
 
-	theaterRoomsDAO     dao.TheaterRoomsDAO
-	performancePriceDAO dao.PerformancePriceDAO
-	voucherProgramDAO   dao.VoucherProgramDAO
+	theaterRoomsDAO dao.TheaterRoomRepository
+	pricingEngine   *PricingEngine
+	transactor      dao.Transactor
+	loyaltyLedger   dao.LoyaltyLedgerRepository
+	eligibilityDAO  dao.ConcessionEligibilityRepository
+
+	seatAllocators        map[types.SeatAllocation]SeatAllocator
+	defaultSeatAllocation types.SeatAllocation
+	vipQuotaPolicies      map[types.PerformanceNature]types.VIPQuotaPolicy
+
+	clock        clock.Clock
+	holdDuration time.Duration
 
 	debug bool
 }
 
-func NewTheaterService(reservationDAO dao.ReservationDAO, theaterRoomsDAO dao.TheaterRoomsDAO, performancePriceDAO dao.PerformancePriceDAO, voucherProgramDAO dao.VoucherProgramDAO, debug bool) TheaterService {
-	return TheaterService{
-		reservationService:  NewReservationService(reservationDAO),
-		theaterRoomsDAO:     theaterRoomsDAO,
-		performancePriceDAO: performancePriceDAO,
-		voucherProgramDAO:   voucherProgramDAO,
-		debug:               debug,
+// systemCustomerID is the customer ID recorded in reservation history for transitions made by the service itself
+const systemCustomerID = 0
+
+// DefaultHoldDuration is how long seats stay BOOKING_PENDING before being released, unless the reservation is confirmed
+const DefaultHoldDuration = 15 * time.Minute
+
+// Option customizes a TheaterService
+type Option func(*TheaterService)
+
+// WithTransactor sets the transactor used to save seats, reservations and loyalty points together,
+// it must operate on the same storage as the service repositories and loyalty ledger.
+// Defaults to a dao.MemoryTransactor over them.
+func WithTransactor(transactor dao.Transactor) Option {
+	return func(t *TheaterService) {
+		t.transactor = transactor
+	}
+}
+
+// WithClock sets the clock telling the current time, defaults to the system clock
+func WithClock(clock clock.Clock) Option {
+	return func(t *TheaterService) {
+		t.clock = clock
+	}
+}
+
+// WithHoldDuration sets how long seats are held for a pending reservation, defaults to DefaultHoldDuration
+func WithHoldDuration(holdDuration time.Duration) Option {
+	return func(t *TheaterService) {
+		t.holdDuration = holdDuration
+	}
+}
+
+// WithRoundingMode sets how prices are rounded to the minor unit of their currency, defaults to DefaultRoundingMode
+func WithRoundingMode(mode money.RoundingMode) Option {
+	return func(t *TheaterService) {
+		t.pricingEngine.roundingMode = mode
+	}
+}
+
+// WithConversionTable sets the exchange rates used to bill customers in another currency than the one of the performance price,
+// customers can only be billed in the currency of the performance without it
+func WithConversionTable(table *money.ConversionTable) Option {
+	return func(t *TheaterService) {
+		t.pricingEngine.conversionTable = table
+	}
+}
+
+// WithVoucherRepository sets the repository of the voucher codes customers can present, defaults to a dao.VoucherDAO
+func WithVoucherRepository(vouchers dao.VoucherRepository) Option {
+	return func(t *TheaterService) {
+		t.pricingEngine.voucherDAO = vouchers
+	}
+}
+
+// WithDiscountRules sets the pricing rules which may grant discounts to reservations, they replace the default ones:
+// the group discount, the subscriber discount, then the voucher program discount multiplied together
+func WithDiscountRules(rules ...DiscountRule) Option {
+	return func(t *TheaterService) {
+		t.pricingEngine.discountRules = rules
+	}
+}
+
+// WithDiscountCap sets the largest ratio of the initial price the discounts may remove altogether, unlimited by default.
+// Voucher codes and loyalty points are not subject to the cap.
+func WithDiscountCap(discountCap *big.Rat) Option {
+	return func(t *TheaterService) {
+		t.pricingEngine.discountCap = discountCap
+	}
+}
+
+// WithLoyaltyLedger sets the ledger of the loyalty points of customers, defaults to a dao.LoyaltyLedgerDAO.
+// With WithTransactor, it must be the ledger the transactor writes to.
+func WithLoyaltyLedger(ledger dao.LoyaltyLedgerRepository) Option {
+	return func(t *TheaterService) {
+		t.loyaltyLedger = ledger
+	}
+}
+
+// WithConcessionEligibility sets the repository of the proofs of eligibility to concession tickets customers have given,
+// defaults to a dao.ConcessionEligibilityDAO
+func WithConcessionEligibility(eligibilityDAO dao.ConcessionEligibilityRepository) Option {
+	return func(t *TheaterService) {
+		t.eligibilityDAO = eligibilityDAO
+	}
+}
+
+// WithSeatAllocator registers the allocator of a seat allocation strategy, replacing the built-in one of the same name
+func WithSeatAllocator(name types.SeatAllocation, allocator SeatAllocator) Option {
+	return func(t *TheaterService) {
+		t.seatAllocators[name] = allocator
+	}
+}
+
+// WithDefaultSeatAllocation sets the seat allocation strategy used when neither the request nor the performance sets one,
+// defaults to types.SeatAllocationFirstFit
+func WithDefaultSeatAllocation(name types.SeatAllocation) Option {
+	return func(t *TheaterService) {
+		t.defaultSeatAllocation = name
 	}
 }
 
+// WithVIPQuotaPolicies sets the seats kept for VIPs depending on the nature of the performances, they replace the default ones
+// of DefaultVIPQuotaPolicies. Performances of the natures missing from the table keep no seats.
+func WithVIPQuotaPolicies(policies map[types.PerformanceNature]types.VIPQuotaPolicy) Option {
+	return func(t *TheaterService) {
+		t.vipQuotaPolicies = policies
+	}
+}
+
+func NewTheaterService(reservationDAO dao.ReservationRepository, theaterRoomsDAO dao.TheaterRoomRepository, performancePriceDAO dao.PerformancePriceRepository, voucherProgramDAO dao.VoucherProgramRepository, customerSubscriptionDAO dao.CustomerSubscriptionRepository, debug bool, options ...Option) TheaterService {
+	t := TheaterService{
+		reservationService:    NewReservationService(reservationDAO),
+		theaterRoomsDAO:       theaterRoomsDAO,
+		pricingEngine:         NewPricingEngine(performancePriceDAO, voucherProgramDAO, customerSubscriptionDAO),
+		loyaltyLedger:         dao.NewLoyaltyLedgerDAO(),
+		eligibilityDAO:        dao.NewConcessionEligibilityDAO(),
+		seatAllocators:        defaultSeatAllocators(),
+		defaultSeatAllocation: types.SeatAllocationFirstFit,
+		vipQuotaPolicies:      DefaultVIPQuotaPolicies(),
+		clock:                 clock.System{},
+		holdDuration:          DefaultHoldDuration,
+		debug:                 debug,
+	}
+	for _, option := range options {
+		option(&t)
+	}
+	if t.transactor == nil {
+		t.transactor = dao.NewMemoryTransactor(theaterRoomsDAO, reservationDAO, t.loyaltyLedger)
+	}
+	t.reservationService.clock = t.clock
+	t.pricingEngine.clock = t.clock
+	return t
+}
+
+var (
+	ErrNoSeatsAvailable  = errors.New("no contiguous seats available in the requested category")
+	ErrNotEnoughVIPSeats = errors.New("not enough VIP seats available")
+	ErrSaleNotOpen       = errors.New("sale is not open yet for the performance")
+
+	ErrReservationNotFound         = errors.New("reservation not found")
+	ErrReservationExpired          = errors.New("reservation hold has expired")
+	ErrReservationAlreadyConfirmed = errors.New("reservation is already confirmed")
+	ErrReservationAlreadyCancelled = errors.New("reservation is already cancelled")
+	ErrReservationNotActive        = errors.New("reservation is neither pending nor confirmed")
+	ErrSeatNotInReservation        = errors.New("seat is not part of the reservation")
+)
+
+// Reservation books seats for the given performance and returns the outcome as an XML document.
 func (t *TheaterService) Reservation(customerID int64, reservationCount int, reservationCategory types.ZoneCategory, performance types.Performance) string {
-	var reservation types.Reservation
+	result := t.Reserve(types.ReservationRequest{
+		CustomerID:       customerID,
+		ReservationCount: reservationCount,
+		Category:         reservationCategory,
+		Performance:      performance,
+	})
+
 	var sb strings.Builder
-	var bookedSeats int
-	var foundSeats []string
-	seatsCategory := make(map[string]types.ZoneCategory)
-	var zoneCategory types.ZoneCategory
-	var remainingSeats int
-	var totalSeats int
-	var foundAllSeats bool
-
-	sb.WriteString("<reservation>\n")
-	sb.WriteString("\t<performance>\n")
-	sb.WriteString("\t\t<play>")
-	sb.WriteString(performance.Play)
-	sb.WriteString("</play>\n")
-	sb.WriteString("\t\t<date>")
-	sb.WriteString(performance.StartTime.Format("2006-01-02"))
-	sb.WriteString("</date>\n")
-	sb.WriteString("\t\t<time>")
-	sb.WriteString(performance.StartTime.Format("15:04:05"))
-	sb.WriteString("</time>\n")
-	sb.WriteString("\t</performance>\n")
-
-	resID := t.reservationService.InitNewReservation()
-	reservation.ReservationID = resID
-	reservation.PerformanceID = performance.ID
-	sb.WriteString("\t<reservationId>")
-	sb.WriteString(strconv.FormatInt(resID, 10))
-	sb.WriteString("</reservationId>\n")
-
-	room := t.theaterRoomsDAO.FetchTheaterRoom(performance.ID)
-
-	// find "reservationCount" first contiguous seats in any row
-	for _, zone := range room.Zones {
-		zoneCategory = zone.Category
-		for _, row := range zone.Rows {
-			seatsForRow := make([]string, 0, reservationCount)
-			streakOfNotReservedSeats := 0
-			for _, aSeat := range row.Seats {
-				totalSeats++
-				if aSeat.Status != types.SeatStatusBooked && aSeat.Status != types.SeatStatusBookingPending {
-					remainingSeats++
-					if reservationCategory != zoneCategory {
-						continue
-					}
-					if !foundAllSeats {
-						seatsForRow = append(seatsForRow, aSeat.SeatID)
-						streakOfNotReservedSeats++
-						if streakOfNotReservedSeats >= reservationCount {
-							for _, seat := range seatsForRow {
-								foundSeats = append(foundSeats, seat)
-								seatsCategory[seat] = zoneCategory
-							}
-							foundAllSeats = true
-							remainingSeats -= streakOfNotReservedSeats
-						}
-					}
-				} else {
-					seatsForRow = make([]string, 0, reservationCount)
-					streakOfNotReservedSeats = 0
-				}
+	// encoding into a strings.Builder cannot fail
+	_ = encoder.XMLEncoder{}.Encode(&sb, result)
+	return sb.String()
+}
+
+// ReservationWithFormat books seats for the requested performance and encodes the outcome
+// in the given format, see encoder.Formats for the available ones.
+func (t *TheaterService) ReservationWithFormat(format string, request types.ReservationRequest) (string, error) {
+	// check the format and locale first, not to book seats that could not be reported
+	_, err := encoder.Lookup(format)
+	if err != nil {
+		return "", err
+	}
+	_, err = money.LookupLocale(request.Locale)
+	if err != nil {
+		return "", err
+	}
+	return encoder.EncodeToString(format, t.Reserve(request))
+}
+
+// maxHoldAttempts is the number of times seats are searched for again,
+// when the ones found have been taken by a concurrent reservation in the meantime
+const maxHoldAttempts = 3
+
+// Reserve books seats for the requested performance and returns the typed outcome.
+func (t *TheaterService) Reserve(request types.ReservationRequest) types.ReservationResult {
+	var reservation types.Reservation
+	var search seatSearch
+
+	if len(request.SeatIDs) > 0 {
+		request.ReservationCount = len(request.SeatIDs)
+	}
+	customerID := request.CustomerID
+	performance := request.Performance
+
+	result := types.ReservationResult{
+		Request: request,
+	}
+
+	// abort reports the requests refused before saving (eligibility, seat allocation, chosen seats, pricing, sale window,
+	// VIP quota, loyalty balance) and the storage failures: nothing is stored for them
+	abort := func(err error) types.ReservationResult {
+		result.Reservation = reservation
+		result.Status = types.ReservationStatusAborted
+		result.Seats = nil
+		result.Err = err
+		return result
+	}
+
+	resID, err := t.reservationService.InitNewReservation()
+	if err != nil {
+		return abort(fmt.Errorf("init reservation: %w", err))
+	}
+	newReservation := types.Reservation{
+		ReservationID: resID,
+		PerformanceID: performance.ID,
+		CustomerID:    customerID,
+	}
+	reservation = newReservation
+
+	eligibility, err := t.checkEligibility(request)
+	if err != nil {
+		return abort(err)
+	}
+	allocator, err := t.seatAllocator(request)
+	if err != nil {
+		return abort(err)
+	}
+	err = checkSeatIDs(request)
+	if err != nil {
+		return abort(err)
+	}
+
+	var pricingRules types.PriceBreakdown
+	for attempt := 1; ; attempt++ {
+		room, err := t.theaterRoomsDAO.FetchTheaterRoom(performance.ID)
+		if err != nil {
+			return abort(fmt.Errorf("fetch theater room: %w", err))
+		}
+		// prices may depend on the occupancy of the room at booking time
+		pricingRules, err = t.pricingEngine.Rules(request, room)
+		if err != nil {
+			return abort(err)
+		}
+		opensAt := performance.SaleOpensAt(pricingRules.SubscriptionTier)
+		if t.clock.Now().Before(opensAt) {
+			return abort(fmt.Errorf("%w: opens at %s", ErrSaleNotOpen, opensAt.Format(time.RFC3339)))
+		}
+
+		now := t.clock.Now()
+		quota := t.vipQuota(performance, pricingRules.SubscriptionTier, now)
+		// the quota is checked before anything is held, the seats kept for VIPs are left untouched
+		if len(request.SeatIDs) > 0 {
+			// chosen seats are checked one by one, the ones kept for VIPs are rejected
+			search = t.findChosenSeats(room, request, quota)
+		} else {
+			search = t.findSeats(room, request, allocator)
+			quotaErr := quota.check(room, search.seatsCategory)
+			if quotaErr != nil {
+				// nothing to pay, in the currency of the performance
+				result.Price = PriceSeats(pricingRules, nil)
+				return abort(quotaErr)
 			}
-			if foundAllSeats {
-				for _, seat := range row.Seats {
-					bookedSeats++
-					if slices.ContainsFunc(foundSeats, func(seatID string) bool {
-						return seatID == seat.SeatID
-					}) {
-						if t.debug {
-							fmt.Printf("MIAOU!!! : Seat %s will be saved as %s\n", seat.SeatID, types.SeatStatusBookingPending)
-						}
-					}
-				}
+		}
+		reservation = newReservation
+		reservation.Seats = search.foundSeats
+		reservation.SeatCategories = search.seatsCategory
+		reservation.SplitSeats = search.split
+		reservation.SeatTicketTypes = seatTicketTypes(request, search.foundSeats)
+		reservation.SeatEligibility = seatEligibility(eligibility, search.foundSeats)
+		reservation.Price = PriceSeats(pricingRules, pricedSeats(search.foundSeats, search.seatsCategory, reservation.SeatTicketTypes))
+		if search.foundAllSeats {
+			err = reservation.Transition(types.ReservationStatusPending, now, customerID)
+			reservation.ExpiresAt = now.Add(t.holdDuration)
+			if pricingRules.Voucher != nil {
+				reservation.Voucher = &types.VoucherRedemption{Code: pricingRules.Voucher.Code, RedeemedAt: now}
+			}
+		} else {
+			err = reservation.Transition(types.ReservationStatusAborted, now, customerID)
+		}
+		if err != nil {
+			return abort(err)
+		}
+		// check the balance first, not to hold seats when the points cannot be redeemed
+		err = t.checkLoyaltyBalance(customerID, reservation.Price.RedeemedPoints)
+		if err != nil {
+			return abort(err)
+		}
 
-				t.theaterRoomsDAO.SaveSeats(performance.ID, foundSeats, types.SeatStatusBookingPending)
+		// seats and reservation of a booking are saved together,
+		// seats are held only if no concurrent reservation took them since the room was fetched
+		err = t.transactor.Transaction(func(rooms dao.TheaterRoomRepository, reservations dao.ReservationRepository, ledger dao.LoyaltyLedgerRepository) error {
+			if reservation.Voucher != nil {
+				err := checkVoucherUses(reservations, *pricingRules.Voucher, customerID)
+				if err != nil {
+					return err
+				}
+			}
+			// points are debited before anything else is written, the ledger refuses them when a concurrent reservation spent them
+			err := redeemPoints(ledger, reservation, now)
+			if err != nil {
+				return err
 			}
+			return t.saveNewReservation(rooms, reservations, reservation, search)
+		})
+		var conflictErr *dao.SeatConflictError
+		if errors.As(err, &conflictErr) && attempt < maxHoldAttempts {
+			continue
 		}
+		if err != nil {
+			return abort(err)
+		}
+		break
 	}
-	reservation.Seats = foundSeats
 
-	if foundAllSeats {
-		reservation.Status = types.ReservationStatusPending
-	} else {
-		reservation.Status = types.ReservationStatusAborted
-	}
-
-	t.reservationService.Update(reservation)
-
-	if performance.PerformanceNature == types.PerformanceNaturePremiere && remainingSeats < int(math.Floor(float64(totalSeats)*0.5)) {
-		// keep 50% seats for VIP
-		foundSeats = []string{}
-		fmt.Println("Not enough VIP seats available for Premiere")
-	} else if performance.PerformanceNature == types.PerformanceNaturePreview && remainingSeats < int(math.Floor(float64(totalSeats)*0.9)) {
-		// keep 10% seats for VIP
-		foundSeats = []string{}
-		fmt.Println("Not enough VIP seats available for Preview")
-	}
-
-	if len(foundSeats) > 0 {
-		sb.WriteString("\t<reservationStatus>FULFILLABLE</reservationStatus>\n")
-		sb.WriteString("\t<seats>\n")
-		for _, s := range foundSeats {
-			sb.WriteString("\t\t<seat>\n")
-			sb.WriteString("\t\t\t<id>")
-			sb.WriteString(s)
-			sb.WriteString("</id>\n")
-			sb.WriteString("\t\t\t<category>")
-			sb.WriteString(string(seatsCategory[s]))
-			sb.WriteString("</category>\n")
-			sb.WriteString("\t\t</seat>\n")
-		}
-		sb.WriteString("\t</seats>\n")
+	result.SeatCategories = search.seatsCategory
+	if len(search.rejections) > 0 {
+		result.Err = seatRejectionsError(request, search.rejections)
+		result.SeatRejections = search.rejections
+	} else if !search.foundAllSeats {
+		result.Err = ErrNoSeatsAvailable
+		result.SplitOffer = search.splitOffer
+	}
+	result.Reservation = reservation
+
+	if search.foundAllSeats {
+		result.Status = types.ReservationStatusFulfillable
+		result.Seats = search.foundSeats
 	} else {
-		sb.WriteString("\t<reservationStatus>ABORTED</reservationStatus>\n")
-	}
-
-	// calculate raw price
-	myPrice := t.performancePriceDAO.FetchPerformancePrice(performance.ID)
-
-	initialPrice := big.NewFloat(0)
-	for _, foundSeat := range foundSeats {
-		seatPrice := &big.Float{}
-		seatPrice = seatPrice.Copy(myPrice)
-		categoryRatio := big.NewFloat(1.)
-		if seatsCategory[foundSeat] == types.ZoneCategoryPremium {
-			categoryRatio = big.NewFloat(1.5)
-		}
-		seatPrice = seatPrice.Mul(seatPrice, categoryRatio)
-		initialPrice = initialPrice.Add(initialPrice, seatPrice)
-	}
-
-	// check and apply discounts and fidelity program
-	discountTime := t.voucherProgramDAO.FetchVoucherProgram(performance.StartTime.UTC())
-
-	// has he subscribed or not
-	customerSubscriptionDAO := dao.CustomerSubscriptionDAO{}
-	isSubscribed := customerSubscriptionDAO.FetchCustomerSubscription(customerID)
-
-	totalBilling := initialPrice
-	if isSubscribed {
-		// apply a 25% discount when the user is subscribed
-		removePercent := big.NewFloat(0.175)
-		one := big.NewFloat(1)
-		discount := one.Sub(one, removePercent)
-		totalBilling = initialPrice.Mul(initialPrice, discount)
-	}
-	one := big.NewFloat(1)
-	discountRatio := one.Sub(one, discountTime)
-	totalBilling = totalBilling.Mul(totalBilling, discountRatio)
-	totalBillingFloat, _ := totalBilling.Float64()
-	totalBillingFloat = math.Round(100*totalBillingFloat) / 100
-	total := fmt.Sprintf("%.2f€", totalBillingFloat)
-
-	sb.WriteString("\t<seatCategory>")
-	sb.WriteString(string(reservationCategory))
-	sb.WriteString("</seatCategory>\n")
-	sb.WriteString("\t<totalAmountDue>")
-	sb.WriteString(total)
-	sb.WriteString("</totalAmountDue>\n")
-	sb.WriteString("</reservation>\n")
-	return sb.String()
+		result.Status = types.ReservationStatusAborted
+	}
+
+	result.SeatTicketTypes = reservation.SeatTicketTypes
+	result.SeatEligibility = reservation.SeatEligibility
+	result.Price = PriceSeats(pricingRules, pricedSeats(search.foundSeats, search.seatsCategory, reservation.SeatTicketTypes))
+
+	return result
+}
+
+// saveNewReservation holds the seats found for a new reservation, and saves it
+func (t *TheaterService) saveNewReservation(rooms dao.TheaterRoomRepository, reservations dao.ReservationRepository, reservation types.Reservation, search seatSearch) error {
+	if search.foundAllSeats {
+		err := rooms.HoldSeats(reservation.PerformanceID, search.foundSeats)
+		if err != nil {
+			return fmt.Errorf("hold seats: %w", err)
+		}
+	}
+	err := reservations.Update(reservation)
+	if err != nil {
+		return fmt.Errorf("update reservation: %w", err)
+	}
+	return nil
+}
+
+// Quote prices the requested seats for the customer, without reserving them
+func (t *TheaterService) Quote(request types.ReservationRequest) (types.PriceBreakdown, error) {
+	categories := make([]types.ZoneCategory, request.ReservationCount)
+	for i := range categories {
+		categories[i] = request.Category
+	}
+	room, err := t.theaterRoomsDAO.FetchTheaterRoom(request.Performance.ID)
+	if err != nil {
+		return types.PriceBreakdown{}, fmt.Errorf("fetch theater room: %w", err)
+	}
+	return t.pricingEngine.Quote(request, room, categories...)
+}
+
+// ConfirmReservation books the seats held by a pending reservation, before its hold expires.
+func (t *TheaterService) ConfirmReservation(customerID int64, reservationID int64) error {
+	return t.transactor.Transaction(func(rooms dao.TheaterRoomRepository, reservations dao.ReservationRepository, ledger dao.LoyaltyLedgerRepository) error {
+		reservation, err := reservations.Find(reservationID)
+		if err != nil {
+			return fmt.Errorf("find reservation: %w", err)
+		}
+		if reservation == nil {
+			return fmt.Errorf("%w: #%d", ErrReservationNotFound, reservationID)
+		}
+		if reservation.Status == types.ReservationStatusConfirmed {
+			return fmt.Errorf("%w: #%d", ErrReservationAlreadyConfirmed, reservationID)
+		}
+		now := t.clock.Now()
+		if reservation.Status == types.ReservationStatusPending && isHoldExpired(*reservation, now) {
+			return fmt.Errorf("%w: #%d", ErrReservationExpired, reservationID)
+		}
+		err = reservation.Transition(types.ReservationStatusConfirmed, now, customerID)
+		if err != nil {
+			return err
+		}
+
+		err = rooms.SaveSeats(reservation.PerformanceID, reservation.Seats, types.SeatStatusBooked)
+		if err != nil {
+			return fmt.Errorf("save seats: %w", err)
+		}
+		reservation.ConfirmedAt = now
+		reservation.EarnedPoints = types.EarnedPoints(reservation.Price.TotalAmountDue)
+		err = reservations.Update(*reservation)
+		if err != nil {
+			return fmt.Errorf("update reservation: %w", err)
+		}
+		return earnPoints(ledger, *reservation, now)
+	})
+}
+
+// ReservationHistory returns the status transitions of a reservation, oldest first
+func (t *TheaterService) ReservationHistory(reservationID int64) ([]types.ReservationTransition, error) {
+	return t.reservationService.History(reservationID)
 }
 
-func (t *TheaterService) CancelReservation(reservationID int64, performanceID int64, seatsIDs []string) {
-	t.theaterRoomsDAO.SaveSeats(performanceID, seatsIDs, types.SeatStatusFree)
-	t.reservationService.Cancel(reservationID)
+// CancelReservation cancels a reservation on behalf of the given customer,
+// and frees the seats it holds in the room of its performance.
+func (t *TheaterService) CancelReservation(customerID int64, reservationID int64) error {
+	return t.transactor.Transaction(func(rooms dao.TheaterRoomRepository, reservations dao.ReservationRepository, ledger dao.LoyaltyLedgerRepository) error {
+		now := t.clock.Now()
+		former, reservation, err := cancelReservation(reservations, reservationID, customerID, now)
+		if err != nil {
+			return err
+		}
+		err = rooms.SaveSeats(reservation.PerformanceID, former.Seats, types.SeatStatusFree)
+		if err != nil {
+			return fmt.Errorf("save seats: %w", err)
+		}
+		return settlePoints(ledger, former, *reservation, now)
+	})
+}
+
+// CancelSeats gives back some seats of a reservation on behalf of the given customer,
+// the reservation remains active with its other seats, unless all of them are given back.
+// It returns the amount refunded, priced with the rules in effect when the reservation was made:
+// it is the difference between the former price of the reservation and the price of its remaining seats.
+func (t *TheaterService) CancelSeats(customerID int64, reservationID int64, seatsIDs []string) (money.Money, error) {
+	var refund money.Money
+	err := t.transactor.Transaction(func(rooms dao.TheaterRoomRepository, reservations dao.ReservationRepository, ledger dao.LoyaltyLedgerRepository) error {
+		reservation, err := reservations.Find(reservationID)
+		if err != nil {
+			return fmt.Errorf("find reservation: %w", err)
+		}
+		if reservation == nil {
+			return fmt.Errorf("%w: #%d", ErrReservationNotFound, reservationID)
+		}
+		if reservation.Status != types.ReservationStatusPending && reservation.Status != types.ReservationStatusConfirmed {
+			return fmt.Errorf("%w: #%d is %s", ErrReservationNotActive, reservationID, reservation.Status)
+		}
+		for _, seatID := range seatsIDs {
+			if !slices.Contains(reservation.Seats, seatID) {
+				return fmt.Errorf("%w: %s in #%d", ErrSeatNotInReservation, seatID, reservationID)
+			}
+		}
+
+		former := *reservation
+		remainingSeats := slices.DeleteFunc(slices.Clone(reservation.Seats), func(seatID string) bool {
+			return slices.Contains(seatsIDs, seatID)
+		})
+		now := t.clock.Now()
+		if len(remainingSeats) == 0 {
+			err = reservation.Transition(types.ReservationStatusCancelled, now, customerID)
+			if err != nil {
+				return err
+			}
+			releaseVoucher(reservation, now)
+		}
+
+		price := PriceSeats(reservation.Price, pricedSeats(remainingSeats, reservation.SeatCategories, reservation.SeatTicketTypes))
+		refund = reservation.Price.TotalAmountDue.Sub(price.TotalAmountDue)
+		releasedSeats := slices.DeleteFunc(slices.Clone(reservation.Seats), func(seatID string) bool {
+			return !slices.Contains(seatsIDs, seatID)
+		})
+		reservation.ReleasedSeats = append(slices.Clip(reservation.ReleasedSeats), releasedSeats...)
+		reservation.Seats = remainingSeats
+		reservation.Price = price
+		reservation.RefundedAmount = reservation.RefundedAmount.Add(refund)
+		reservation.EarnedPoints = keptEarnedPoints(*reservation)
+
+		err = rooms.SaveSeats(reservation.PerformanceID, releasedSeats, types.SeatStatusFree)
+		if err != nil {
+			return fmt.Errorf("save seats: %w", err)
+		}
+		err = reservations.Update(*reservation)
+		if err != nil {
+			return fmt.Errorf("update reservation: %w", err)
+		}
+		return settlePoints(ledger, former, *reservation, now)
+	})
+	if err != nil {
+		return money.Money{}, err
+	}
+	return refund, nil
+}
+
+// seatTicketTypes associates the seats found for a request to the ticket types of the request, in order;
+// it is nil when the request does not set ticket types
+func seatTicketTypes(request types.ReservationRequest, seats []string) map[string]types.TicketType {
+	if len(request.TicketTypes) == 0 {
+		return nil
+	}
+	ticketTypes := make(map[string]types.TicketType, len(seats))
+	for i, seat := range seats {
+		ticketTypes[seat] = request.TicketType(i)
+	}
+	return ticketTypes
+}
+
+// seatSearch is the outcome of a search for contiguous seats in a room
+type seatSearch struct {
+	foundSeats    []string
+	seatsCategory map[string]types.ZoneCategory
+	foundAllSeats bool
+	// split tells the seats found are not all side by side, the customer accepted split seating
+	split bool
+	// splitOffer are the seats offered and rejected by the customer, when no row has enough contiguous free seats
+	splitOffer []string
+	// rejections tell why the seats chosen by the customer cannot be reserved
+	rejections map[string]types.SeatRejection
+}
+
+// findSeats finds the contiguous seats of the requested category chosen by the allocator,
+// or seats which are not all side by side when there are none and the request accepts split seating.
+func (t *TheaterService) findSeats(room types.TheaterRoom, request types.ReservationRequest, allocator SeatAllocator) seatSearch {
+	reservationCategory := request.Category
+	foundSeats := allocator.AllocateSeats(room, request.ReservationCount, reservationCategory)
+	var split bool
+	var splitOffer []string
+	if len(foundSeats) == 0 && request.SplitSeating != "" {
+		splitOffer = splitSeats(room, request.ReservationCount, reservationCategory)
+		if request.SplitSeating == types.SplitSeatingAccept {
+			foundSeats, splitOffer = splitOffer, nil
+			split = len(foundSeats) > 0
+		}
+	}
+	foundAllSeats := len(foundSeats) > 0
+	seatsCategory := make(map[string]types.ZoneCategory)
+	for _, seat := range foundSeats {
+		seatsCategory[seat] = reservationCategory
+		if t.debug {
+			fmt.Printf("MIAOU!!! : Seat %s will be saved as %s\n", seat, types.SeatStatusBookingPending)
+		}
+	}
+
+	return seatSearch{
+		foundSeats:    foundSeats,
+		seatsCategory: seatsCategory,
+		foundAllSeats: foundAllSeats,
+		split:         split,
+		splitOffer:    splitOffer,
+	}
 }
@@ -1,9 +1,12 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -11,7 +14,9 @@ import (
 
 	"github.com/andreyvit/diff"
 
+	"github.com/benoitmasson/theater-reservation-kata/internal/clock"
 	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
 	"github.com/benoitmasson/theater-reservation-kata/internal/types"
 )
 
@@ -20,8 +25,12 @@ var (
 	theaterRoomsDAO     = dao.NewTheaterRoomsDAO()
 	performancePriceDAO = dao.NewPerformancePriceDAO()
 	voucherProgramDAO   = dao.NewVoucherProgramDAO()
+	subscriptionDAO     = dao.NewCustomerSubscriptionDAO()
 
-	theaterService     = NewTheaterService(reservationDAO, theaterRoomsDAO, performancePriceDAO, voucherProgramDAO, false)
+	// bookings are made while the 2023 voucher program runs
+	bookingClock = clock.Func(func() time.Time { return time.Date(2023, time.April, 1, 10, 0, 0, 0, time.UTC) })
+
+	theaterService     = NewTheaterService(reservationDAO, theaterRoomsDAO, performancePriceDAO, voucherProgramDAO, subscriptionDAO, false, WithClock(bookingClock))
 	reservationService = NewReservationService(reservationDAO)
 
 	performanceCICD = types.Performance{
@@ -45,8 +54,8 @@ var (
 )
 
 func TestTheaterReservation(t *testing.T) {
-	type cancelBefore struct {
-		reservationID int64
+	// releasedBefore are seats freed by another application than ours before reserving
+	type releasedBefore struct {
 		performanceID int64
 		seatsIDs      []string
 	}
@@ -54,13 +63,15 @@ func TestTheaterReservation(t *testing.T) {
 		nbSeats      int
 		zoneCategory types.ZoneCategory
 		expectedID   int64
+		// rejected tells the reservation is refused by the VIP quota, and not stored
+		rejected bool
 	}
 	type test struct {
-		name         string
-		customerID   int64
-		performance  types.Performance
-		reservations []reservation
-		cancelBefore *cancelBefore
+		name           string
+		customerID     int64
+		performance    types.Performance
+		reservations   []reservation
+		releasedBefore *releasedBefore
 	}
 	tests := []test{
 		{
@@ -71,8 +82,9 @@ func TestTheaterReservation(t *testing.T) {
 				nbSeats:      4,
 				zoneCategory: types.ZoneCategoryStandard,
 				expectedID:   123456,
+				rejected:     true,
 			}},
-			cancelBefore: nil,
+			releasedBefore: nil,
 		},
 		{
 			name:        "cancel_then_reserve_on_premiere_performance_with_standard_category",
@@ -83,8 +95,7 @@ func TestTheaterReservation(t *testing.T) {
 				zoneCategory: types.ZoneCategoryStandard,
 				expectedID:   123457,
 			}},
-			cancelBefore: &cancelBefore{
-				reservationID: 123456,
+			releasedBefore: &releasedBefore{
 				performanceID: 1,
 				seatsIDs:      []string{"B2"},
 			},
@@ -97,8 +108,9 @@ func TestTheaterReservation(t *testing.T) {
 				nbSeats:      4,
 				zoneCategory: types.ZoneCategoryStandard,
 				expectedID:   123458,
+				rejected:     true,
 			}},
-			cancelBefore: nil,
+			releasedBefore: nil,
 		},
 		{
 			name:        "reserve_once_on_premiere_performance_with_premium_category",
@@ -109,7 +121,7 @@ func TestTheaterReservation(t *testing.T) {
 				zoneCategory: types.ZoneCategoryPremium,
 				expectedID:   123459,
 			}},
-			cancelBefore: nil,
+			releasedBefore: nil,
 		},
 		{
 			name:        "reserve_once_on_premiere_performance",
@@ -120,7 +132,7 @@ func TestTheaterReservation(t *testing.T) {
 				zoneCategory: types.ZoneCategoryStandard,
 				expectedID:   123460,
 			}},
-			cancelBefore: nil,
+			releasedBefore: nil,
 		},
 		{
 			name:        "reserve_twice_on_premiere_performance",
@@ -138,23 +150,33 @@ func TestTheaterReservation(t *testing.T) {
 					expectedID:   123462,
 				},
 			},
-			cancelBefore: nil,
+			releasedBefore: nil,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			if test.cancelBefore != nil {
-				theaterService.CancelReservation(test.cancelBefore.reservationID, test.cancelBefore.performanceID, test.cancelBefore.seatsIDs)
+			if test.releasedBefore != nil {
+				err := theaterRoomsDAO.SaveSeats(test.releasedBefore.performanceID, test.releasedBefore.seatsIDs, types.SeatStatusFree)
+				if err != nil {
+					t.Fatalf("Failed to release seats: %v", err)
+				}
 			}
 			var actualXML string
 			for _, reservation := range test.reservations {
 				actualXML = theaterService.Reservation(test.customerID, reservation.nbSeats, reservation.zoneCategory, test.performance)
 
 				// TODO: Add testing for reserved seat references
-				if reservationService.Find(reservation.expectedID) == nil {
+				found, err := reservationService.Find(reservation.expectedID)
+				if err != nil {
+					t.Fatalf("Failed to find reservation: %v", err)
+				}
+				if found == nil && !reservation.rejected {
 					t.Errorf("Reservation #%d not found", reservation.expectedID)
 				}
+				if found != nil && reservation.rejected {
+					t.Errorf("Rejected reservation #%d was stored", reservation.expectedID)
+				}
 			}
 
 			verifyXML(t, actualXML, test.name)
@@ -162,6 +184,144 @@ func TestTheaterReservation(t *testing.T) {
 	}
 }
 
+func TestReserve(t *testing.T) {
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+
+	result := service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 2, Category: types.ZoneCategoryPremium, Performance: performance})
+	if result.Status != types.ReservationStatusFulfillable || result.Err != nil {
+		t.Fatalf("Expected reservation to be fulfillable, got %s: %v", result.Status, result.Err)
+	}
+	if !slices.Equal(result.Seats, []string{"H5", "H6"}) {
+		t.Errorf("Expected seats H5 and H6, got %v", result.Seats)
+	}
+	if !maps.Equal(result.SeatCategories, map[string]types.ZoneCategory{"H5": types.ZoneCategoryPremium, "H6": types.ZoneCategoryPremium}) {
+		t.Errorf("Expected premium seats, got %v", result.SeatCategories)
+	}
+	// 2 × 52.50 EUR, 17.5% off for gold subscribers then 20% off with the voucher program
+	if result.Price.InitialPrice != money.MustParse("105.00", money.EUR) || result.Price.TotalAmountDue != money.MustParse("69.30", money.EUR) {
+		t.Errorf("Expected 105.00 EUR reduced to 69.30 EUR, got %v reduced to %v", result.Price.InitialPrice, result.Price.TotalAmountDue)
+	}
+
+	result = service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 200, Category: types.ZoneCategoryPremium, Performance: performance})
+	if result.Status != types.ReservationStatusAborted || !errors.Is(result.Err, ErrNoSeatsAvailable) {
+		t.Fatalf("Expected reservation to be aborted with ErrNoSeatsAvailable, got %s: %v", result.Status, result.Err)
+	}
+	if len(result.Seats) > 0 || len(result.SeatCategories) > 0 {
+		t.Errorf("Expected no seats, got %v %v", result.Seats, result.SeatCategories)
+	}
+	if len(result.Price.Lines) > 0 || !result.Price.TotalAmountDue.IsZero() {
+		t.Errorf("Expected nothing to pay, got %v for %v", result.Price.Lines, result.Price.TotalAmountDue)
+	}
+}
+
+func TestCancelReservation(t *testing.T) {
+	rooms := dao.NewTheaterRoomsDAO()
+	reservations := dao.NewReservationDAO()
+	service := NewTheaterService(reservations, rooms, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+
+	result := service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 3, Category: types.ZoneCategoryStandard, Performance: performance})
+	if result.Err != nil {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	reservationID := result.Reservation.ReservationID
+
+	err := service.CancelReservation(1, reservationID)
+	if err != nil {
+		t.Fatalf("Failed to cancel reservation: %v", err)
+	}
+	reservation, err := reservations.Find(reservationID)
+	if err != nil || reservation == nil {
+		t.Fatalf("Failed to find reservation: %v", err)
+	}
+	if reservation.Status != types.ReservationStatusCancelled || len(reservation.Seats) > 0 || !slices.Equal(reservation.ReleasedSeats, result.Seats) {
+		t.Errorf("Unexpected cancelled reservation: %+v", reservation)
+	}
+	room, err := rooms.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	for _, row := range room.Zones[0].Rows {
+		for _, seat := range row.Seats {
+			if slices.Contains(result.Seats, seat.SeatID) && seat.Status != types.SeatStatusFree {
+				t.Errorf("Expected seat %s to be freed, got %s", seat.SeatID, seat.Status)
+			}
+		}
+	}
+
+	err = service.CancelReservation(1, reservationID)
+	if !errors.Is(err, ErrReservationAlreadyCancelled) {
+		t.Errorf("Expected ErrReservationAlreadyCancelled, got %v", err)
+	}
+	err = service.CancelReservation(1, 1)
+	if !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("Expected ErrReservationNotFound, got %v", err)
+	}
+}
+
+func TestCancelSeats(t *testing.T) {
+	rooms := dao.NewTheaterRoomsDAO()
+	reservations := dao.NewReservationDAO()
+	service := NewTheaterService(reservations, rooms, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+
+	result := service.Reserve(types.ReservationRequest{CustomerID: 1, ReservationCount: 4, Category: types.ZoneCategoryStandard, Performance: performance})
+	if result.Err != nil {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	reservationID := result.Reservation.ReservationID
+	if !slices.Equal(result.Seats, []string{"B3", "B4", "B5", "B6"}) || result.Price.TotalAmountDue != money.MustParse("92.40", money.EUR) {
+		t.Fatalf("Unexpected reservation: %v for %v", result.Seats, result.Price.TotalAmountDue)
+	}
+
+	_, err := service.CancelSeats(1, reservationID, []string{"B3", "C1"})
+	if !errors.Is(err, ErrSeatNotInReservation) {
+		t.Errorf("Expected ErrSeatNotInReservation, got %v", err)
+	}
+
+	refund, err := service.CancelSeats(1, reservationID, []string{"B4"})
+	if err != nil {
+		t.Fatalf("Failed to cancel seat: %v", err)
+	}
+	if refund != money.MustParse("23.10", money.EUR) {
+		t.Errorf("Expected a 23.10 refund, got %v", refund)
+	}
+	reservation, err := reservations.Find(reservationID)
+	if err != nil || reservation == nil {
+		t.Fatalf("Failed to find reservation: %v", err)
+	}
+	if reservation.Status != types.ReservationStatusPending || !slices.Equal(reservation.Seats, []string{"B3", "B5", "B6"}) ||
+		!slices.Equal(reservation.ReleasedSeats, []string{"B4"}) || reservation.Price.TotalAmountDue != money.MustParse("69.30", money.EUR) {
+		t.Errorf("Unexpected reservation after partial cancellation: %+v", reservation)
+	}
+
+	refund, err = service.CancelSeats(1, reservationID, []string{"B3", "B5", "B6"})
+	if err != nil {
+		t.Fatalf("Failed to cancel seats: %v", err)
+	}
+	if refund != money.MustParse("69.30", money.EUR) {
+		t.Errorf("Expected a 69.30 refund, got %v", refund)
+	}
+	reservation, err = reservations.Find(reservationID)
+	if err != nil || reservation == nil {
+		t.Fatalf("Failed to find reservation: %v", err)
+	}
+	if reservation.Status != types.ReservationStatusCancelled || reservation.RefundedAmount != money.MustParse("92.40", money.EUR) {
+		t.Errorf("Unexpected reservation after cancelling all seats: %+v", reservation)
+	}
+	room, err := rooms.FetchTheaterRoom(performance.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	for _, seat := range room.Zones[0].Rows[1].Seats {
+		if slices.Contains(result.Seats, seat.SeatID) && seat.Status != types.SeatStatusFree {
+			t.Errorf("Expected seat %s to be freed, got %s", seat.SeatID, seat.Status)
+		}
+	}
+}
+
 func verifyXML(t *testing.T, actualXML string, referenceFilePrefix string) {
 	referenceFolder := "testdata"
 	err := os.MkdirAll(referenceFolder, 0o755)
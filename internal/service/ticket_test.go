@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestTicketTypes(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	adult, child := types.TicketTypeAdult, types.TicketTypeChild
+
+	// children pay half price, then 15% off for the family and 20% off with the voucher program
+	result := service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 4, Category: types.ZoneCategoryStandard, Performance: performance,
+		TicketTypes: []types.TicketType{adult, child, adult, child}})
+	if result.Err != nil {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	if result.Price.TotalAmountDue != money.MustParse("71.40", money.EUR) {
+		t.Errorf("Expected total amount due of 71.40, got %v", result.Price.TotalAmountDue)
+	}
+	childSeat := result.Seats[1]
+	if result.SeatTicketTypes[childSeat] != child || result.Reservation.SeatTicketTypes[childSeat] != child {
+		t.Errorf("Expected seat %s to be a child one, got %v", childSeat, result.Reservation.SeatTicketTypes)
+	}
+	expectedLines := map[types.PriceLineKind]string{
+		types.PriceLineTicketType:      "-17.50",
+		types.PriceLineGroupDiscount:   "-15.75",
+		types.PriceLineVoucherDiscount: "-17.85",
+	}
+	for _, line := range result.Price.Lines {
+		expected, ok := expectedLines[line.Kind]
+		if ok && line.Amount != money.MustParse(expected, money.EUR) {
+			t.Errorf("Expected %s line of %s, got %+v", line.Kind, expected, line)
+		}
+	}
+
+	// the refund of a child seat is priced with its ticket type
+	refund, err := service.CancelSeats(2, result.Reservation.ReservationID, []string{childSeat})
+	if err != nil {
+		t.Fatalf("Failed to cancel seat: %v", err)
+	}
+	if refund != money.MustParse("11.90", money.EUR) {
+		t.Errorf("Expected refund of 11.90, got %v", refund)
+	}
+
+	// 10% off from 6 seats
+	result = service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 6, Category: types.ZoneCategoryStandard, Performance: performance})
+	if result.Err != nil || result.Price.TotalAmountDue != money.MustParse("151.20", money.EUR) {
+		t.Errorf("Expected total amount due of 151.20, got %v (%v)", result.Price.TotalAmountDue, result.Err)
+	}
+}
+
+func TestInvalidTicketTypes(t *testing.T) {
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performanceCICD,
+		TicketTypes: []types.TicketType{types.TicketTypeChild}}
+
+	result := service.Reserve(request)
+	if !errors.Is(result.Err, ErrInvalidTicketTypes) {
+		t.Errorf("Expected ErrInvalidTicketTypes, got %v", result.Err)
+	}
+	request.TicketTypes = []types.TicketType{types.TicketTypeAdult, "TODDLER"}
+	_, err := service.Quote(request)
+	if !errors.Is(err, ErrTicketTypeNotSold) {
+		t.Errorf("Expected ErrTicketTypeNotSold, got %v", err)
+	}
+}
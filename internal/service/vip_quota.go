@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+// DefaultVIPQuotaPolicies returns the VIP quota policies of the performance natures:
+// premieres and previews keep seats of the whole room, galas keep premium seats until two days before the performance,
+// and let gold subscribers book them besides the ones with VIP quota access.
+// Regular performances and matinees keep no seats.
+func DefaultVIPQuotaPolicies() map[types.PerformanceNature]types.VIPQuotaPolicy {
+	return map[types.PerformanceNature]types.VIPQuotaPolicy{
+		// keep 50% seats for VIP
+		types.PerformanceNaturePremiere: {RoomThreshold: big.NewRat(1, 2)},
+		// keep 10% seats for VIP
+		types.PerformanceNaturePreview: {RoomThreshold: big.NewRat(9, 10)},
+		types.PerformanceNatureGala: {
+			CategoryThresholds: map[types.ZoneCategory]*big.Rat{types.ZoneCategoryPremium: big.NewRat(1, 2)},
+			BypassTiers:        []types.SubscriptionTier{types.SubscriptionTierGold, types.SubscriptionTierPatron},
+			ReleaseBefore:      48 * time.Hour,
+		},
+		types.PerformanceNatureRegular: {},
+		types.PerformanceNatureMatinee: {},
+	}
+}
+
+// VIPQuotaError tells a reservation would leave fewer free seats than the ones kept for the VIPs of the performance,
+// it matches ErrNotEnoughVIPSeats with errors.Is
+type VIPQuotaError struct {
+	PerformanceNature types.PerformanceNature
+	// Category is the zone category whose seats are kept, empty when the seats of the whole room are
+	Category types.ZoneCategory
+	// RemainingSeats is the number of free seats the reservation would leave
+	RemainingSeats int
+	// Threshold is the fewest free seats which must be left
+	Threshold int
+}
+
+func (e *VIPQuotaError) Error() string {
+	scope := "the room"
+	if e.Category != "" {
+		scope = string(e.Category) + " seats"
+	}
+	return fmt.Sprintf("%s for %s: %d of %s would be left free, %d must be", ErrNotEnoughVIPSeats, e.PerformanceNature, e.RemainingSeats, scope, e.Threshold)
+}
+
+func (e *VIPQuotaError) Unwrap() error {
+	return ErrNotEnoughVIPSeats
+}
+
+// vipQuota checks reservations against the VIP quota policy of a performance
+type vipQuota struct {
+	nature types.PerformanceNature
+	policy types.VIPQuotaPolicy
+	// lifted tells the seats kept for VIPs may be booked, by the customer or by anyone once released
+	lifted bool
+}
+
+// vipQuota returns the VIP quota of the performance for the customers of the tier, at the booking time
+func (t *TheaterService) vipQuota(performance types.Performance, tier types.SubscriptionTier, now time.Time) vipQuota {
+	policy := t.vipQuotaPolicies[performance.PerformanceNature]
+	releaseDate, released := policy.ReleaseDate(performance)
+	return vipQuota{
+		nature: performance.PerformanceNature,
+		policy: policy,
+		lifted: policy.Bypassed(tier) || (released && !now.Before(releaseDate)),
+	}
+}
+
+// check returns a *VIPQuotaError when taking the free seats would leave fewer free seats than the ones kept for VIPs
+func (q vipQuota) check(room types.TheaterRoom, seatsCategory map[string]types.ZoneCategory) error {
+	if q.lifted || len(seatsCategory) == 0 {
+		return nil
+	}
+
+	var totalSeats, freeSeats int
+	categoryTotalSeats := make(map[types.ZoneCategory]int)
+	categoryFreeSeats := make(map[types.ZoneCategory]int)
+	for _, zone := range room.Zones {
+		for _, row := range zone.Rows {
+			for _, seat := range row.Seats {
+				totalSeats++
+				categoryTotalSeats[zone.Category]++
+				if isFreeSeat(seat) {
+					freeSeats++
+					categoryFreeSeats[zone.Category]++
+				}
+			}
+		}
+	}
+	for _, category := range seatsCategory {
+		freeSeats--
+		categoryFreeSeats[category]--
+	}
+
+	if q.policy.RoomThreshold != nil {
+		threshold := types.KeptSeats(q.policy.RoomThreshold, totalSeats)
+		if freeSeats < threshold {
+			return &VIPQuotaError{PerformanceNature: q.nature, RemainingSeats: freeSeats, Threshold: threshold}
+		}
+	}
+	for _, category := range types.ZoneCategories {
+		ratio, ok := q.policy.CategoryThresholds[category]
+		if !ok || !takesCategory(seatsCategory, category) {
+			continue
+		}
+		threshold := types.KeptSeats(ratio, categoryTotalSeats[category])
+		if categoryFreeSeats[category] < threshold {
+			return &VIPQuotaError{PerformanceNature: q.nature, Category: category, RemainingSeats: categoryFreeSeats[category], Threshold: threshold}
+		}
+	}
+	return nil
+}
+
+func takesCategory(seatsCategory map[string]types.ZoneCategory, category types.ZoneCategory) bool {
+	for _, seatCategory := range seatsCategory {
+		if seatCategory == category {
+			return true
+		}
+	}
+	return false
+}
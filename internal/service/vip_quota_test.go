@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestVIPQuotaHoldsNothing(t *testing.T) {
+	reservationDAO, roomsDAO := dao.NewReservationDAO(), dao.NewTheaterRoomsDAO()
+	service := NewTheaterService(reservationDAO, roomsDAO, dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	before, err := roomsDAO.FetchTheaterRoom(performanceScala.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	fillRatio := before.FillRatio()
+
+	result := service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performanceScala})
+	var quotaErr *VIPQuotaError
+	if !errors.As(result.Err, &quotaErr) || !errors.Is(result.Err, ErrNotEnoughVIPSeats) {
+		t.Fatalf("Expected a VIPQuotaError, got %v", result.Err)
+	}
+	if quotaErr.PerformanceNature != types.PerformanceNaturePreview || quotaErr.RemainingSeats >= quotaErr.Threshold {
+		t.Errorf("Unexpected quota decision: %+v", quotaErr)
+	}
+	if result.Status != types.ReservationStatusAborted || len(result.Seats) > 0 {
+		t.Errorf("Expected the reservation to be aborted without seats, got %s %v", result.Status, result.Seats)
+	}
+
+	// no seat is held, and the reservation is not stored
+	after, err := roomsDAO.FetchTheaterRoom(performanceScala.ID)
+	if err != nil {
+		t.Fatalf("Failed to fetch room: %v", err)
+	}
+	if after.FillRatio().Cmp(fillRatio) != 0 {
+		t.Errorf("Expected fill ratio to stay %v, got %v", fillRatio, after.FillRatio())
+	}
+	if result.Reservation.ReservationID == 0 {
+		t.Errorf("Expected the result to keep the ID of the reservation")
+	}
+	reservation, err := reservationDAO.Find(result.Reservation.ReservationID)
+	if err != nil || reservation != nil {
+		t.Errorf("Expected no reservation to be stored, got %+v (%v)", reservation, err)
+	}
+}
+
+func TestVIPQuotaPolicies(t *testing.T) {
+	// all the free premium seats of galas are kept for VIPs, some premium seats are already booked
+	policies := map[types.PerformanceNature]types.VIPQuotaPolicy{
+		types.PerformanceNatureGala: {
+			CategoryThresholds: map[types.ZoneCategory]*big.Rat{types.ZoneCategoryPremium: big.NewRat(1, 1)},
+			BypassTiers:        []types.SubscriptionTier{types.SubscriptionTierGold},
+			ReleaseBefore:      48 * time.Hour,
+		},
+	}
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock),
+		WithVIPQuotaPolicies(policies))
+	gala := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime, PerformanceNature: types.PerformanceNatureGala}
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryPremium, Performance: gala}
+
+	result := service.Reserve(request)
+	var quotaErr *VIPQuotaError
+	if !errors.As(result.Err, &quotaErr) || quotaErr.Category != types.ZoneCategoryPremium {
+		t.Fatalf("Expected premium seats to be kept for VIPs, got %v", result.Err)
+	}
+	// standard seats are not concerned
+	request.Category = types.ZoneCategoryStandard
+	result = service.Reserve(request)
+	if result.Err != nil {
+		t.Errorf("Expected standard seats to be booked, got %v", result.Err)
+	}
+	// the bypass tiers of the policy replace the VIP quota access of patrons
+	request.Category = types.ZoneCategoryPremium
+	request.CustomerID = 5
+	result = service.Reserve(request)
+	if !errors.Is(result.Err, ErrNotEnoughVIPSeats) {
+		t.Errorf("Expected patron to be refused premium seats, got %v", result.Err)
+	}
+	// gold subscribers may book the seats kept for VIPs
+	request.CustomerID = 1
+	result = service.Reserve(request)
+	if result.Err != nil {
+		t.Errorf("Expected gold subscriber to book premium seats, got %v", result.Err)
+	}
+	// anyone may book them less than two days before the performance
+	request.CustomerID = 2
+	request.Performance.StartTime = bookingClock.Now().Add(47 * time.Hour)
+	result = service.Reserve(request)
+	if result.Err != nil {
+		t.Errorf("Expected premium seats to be released, got %v", result.Err)
+	}
+
+	// performances of natures missing from the table keep no seats
+	request.Performance = types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime, PerformanceNature: types.PerformanceNaturePremiere}
+	result = service.Reserve(request)
+	if result.Err != nil {
+		t.Errorf("Expected no seats to be kept, got %v", result.Err)
+	}
+}
+
+func TestDefaultVIPQuotaPolicies(t *testing.T) {
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+
+	// the seats kept for the preview are not kept for a matinee or a regular performance in the same room
+	for _, nature := range []types.PerformanceNature{types.PerformanceNatureMatinee, types.PerformanceNatureRegular} {
+		performance := performanceScala
+		performance.PerformanceNature = nature
+		result := service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performance})
+		if result.Err != nil {
+			t.Errorf("Expected a %s seat to be booked, got %v", nature, result.Err)
+		}
+	}
+
+	// galas only keep premium seats
+	gala := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime, PerformanceNature: types.PerformanceNatureGala}
+	result := service.Reserve(types.ReservationRequest{CustomerID: 2, ReservationCount: 4, Category: types.ZoneCategoryStandard, Performance: gala})
+	if result.Err != nil {
+		t.Errorf("Expected standard seats to be booked, got %v", result.Err)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+var (
+	ErrVoucherNotFound      = errors.New("unknown voucher code")
+	ErrVoucherExpired       = errors.New("voucher code is not valid at this time")
+	ErrVoucherNotApplicable = errors.New("voucher code does not apply to this booking")
+	ErrVoucherUsedUp        = errors.New("voucher code has been used up")
+	ErrVoucherCustomerLimit = errors.New("voucher code has been used too many times by the customer")
+)
+
+// checkVoucherUses returns an error when the voucher cannot be used once more by the customer,
+// given the redemptions recorded by the reservations which have not been given back
+func checkVoucherUses(reservations dao.ReservationRepository, voucher types.Voucher, customerID int64) error {
+	if voucher.MaxUses == 0 && voucher.MaxUsesPerCustomer == 0 {
+		return nil
+	}
+	redeemed, err := reservations.FindByVoucherCode(voucher.Code)
+	if err != nil {
+		return fmt.Errorf("find voucher redemptions: %w", err)
+	}
+
+	uses, customerUses := 0, 0
+	for _, reservation := range redeemed {
+		if !reservation.Voucher.IsActive() {
+			continue
+		}
+		uses++
+		if reservation.CustomerID == customerID {
+			customerUses++
+		}
+	}
+	if voucher.MaxUses > 0 && uses >= voucher.MaxUses {
+		return fmt.Errorf("%w: %s", ErrVoucherUsedUp, voucher.Code)
+	}
+	if voucher.MaxUsesPerCustomer > 0 && customerUses >= voucher.MaxUsesPerCustomer {
+		return fmt.Errorf("%w: %s", ErrVoucherCustomerLimit, voucher.Code)
+	}
+	return nil
+}
+
+// releaseVoucher gives back the use of the voucher code of a reservation which no longer holds seats
+func releaseVoucher(reservation *types.Reservation, at time.Time) {
+	if reservation.Voucher == nil || !reservation.Voucher.IsActive() {
+		return
+	}
+	redemption := *reservation.Voucher
+	redemption.ReleasedAt = at
+	reservation.Voucher = &redemption
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/clock"
+	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+	"github.com/benoitmasson/theater-reservation-kata/internal/types"
+)
+
+func TestVoucherCode(t *testing.T) {
+	// performance without nature, not to be bothered by VIP quotas
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	now := time.Date(2023, time.April, 1, 10, 0, 0, 0, time.UTC)
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false,
+		WithClock(clock.Func(func() time.Time { return now })),
+	)
+	request := types.ReservationRequest{CustomerID: 1, ReservationCount: 4, Category: types.ZoneCategoryStandard, Performance: performance, VoucherCode: "SPRING23"}
+
+	// 10% off, after the subscriber and voucher program discounts
+	result := service.Reserve(request)
+	if result.Err != nil {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	if result.Price.TotalAmountDue != money.MustParse("83.16", money.EUR) {
+		t.Errorf("Expected total amount due of 83.16, got %v", result.Price.TotalAmountDue)
+	}
+	lastLine := result.Price.Lines[len(result.Price.Lines)-1]
+	if lastLine.Kind != types.PriceLineVoucherCode || lastLine.Rule != "SPRING23" || lastLine.Amount != money.MustParse("-9.24", money.EUR) {
+		t.Errorf("Expected a -9.24 SPRING23 line, got %+v", lastLine)
+	}
+	if result.Reservation.Voucher == nil || result.Reservation.Voucher.Code != "SPRING23" || !result.Reservation.Voucher.RedeemedAt.Equal(now) {
+		t.Errorf("Expected SPRING23 redemption to be recorded, got %+v", result.Reservation.Voucher)
+	}
+
+	// the validity window and the voucher program apply to the booking time, not to the performance date
+	now = time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)
+	result = service.Reserve(request)
+	if !errors.Is(result.Err, ErrVoucherExpired) {
+		t.Errorf("Expected ErrVoucherExpired, got %v", result.Err)
+	}
+	request.VoucherCode = ""
+	result = service.Reserve(request)
+	if result.Err != nil || result.Price.TotalAmountDue != money.MustParse("115.50", money.EUR) {
+		t.Errorf("Expected no voucher program discount after April 2023, got %v (%v)", result.Price.TotalAmountDue, result.Err)
+	}
+
+	request.VoucherCode = "NOPE"
+	result = service.Reserve(request)
+	if !errors.Is(result.Err, ErrVoucherNotFound) {
+		t.Errorf("Expected ErrVoucherNotFound, got %v", result.Err)
+	}
+}
+
+func TestVoucherCodeRestrictions(t *testing.T) {
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	reservations := dao.NewReservationDAO()
+	service := NewTheaterService(reservations, dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false, WithClock(bookingClock))
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 2, Category: types.ZoneCategoryStandard, Performance: performance, VoucherCode: "CICDFAN"}
+
+	premiumRequest := request
+	premiumRequest.Category = types.ZoneCategoryPremium
+	otherPerformanceRequest := request
+	otherPerformanceRequest.Performance = types.Performance{ID: 2, Play: "Les fourberies de Scala - Molière", StartTime: performanceScala.StartTime}
+	for _, rejected := range []types.ReservationRequest{premiumRequest, otherPerformanceRequest} {
+		result := service.Reserve(rejected)
+		if !errors.Is(result.Err, ErrVoucherNotApplicable) {
+			t.Errorf("Expected ErrVoucherNotApplicable, got %v", result.Err)
+		}
+	}
+
+	// 10.00 off, once per customer
+	result := service.Reserve(request)
+	if result.Err != nil || result.Price.TotalAmountDue != money.MustParse("46.00", money.EUR) {
+		t.Fatalf("Expected total amount due of 46.00, got %v (%v)", result.Price.TotalAmountDue, result.Err)
+	}
+	firstID := result.Reservation.ReservationID
+	result = service.Reserve(request)
+	if !errors.Is(result.Err, ErrVoucherCustomerLimit) {
+		t.Errorf("Expected ErrVoucherCustomerLimit, got %v", result.Err)
+	}
+	otherCustomerRequest := request
+	otherCustomerRequest.CustomerID = 3
+	result = service.Reserve(otherCustomerRequest)
+	if result.Err != nil {
+		t.Errorf("Expected another customer to use the voucher, got %v", result.Err)
+	}
+
+	// cancelling gives the use back
+	err := service.CancelReservation(2, firstID)
+	if err != nil {
+		t.Fatalf("Failed to cancel reservation: %v", err)
+	}
+	reservation, err := reservations.Find(firstID)
+	if err != nil || reservation.Voucher == nil || reservation.Voucher.IsActive() {
+		t.Errorf("Expected voucher redemption to be released, got %+v (%v)", reservation.Voucher, err)
+	}
+	result = service.Reserve(request)
+	if result.Err != nil {
+		t.Errorf("Expected voucher use to be given back on cancellation, got %v", result.Err)
+	}
+}
+
+func TestVoucherCodeUsageCap(t *testing.T) {
+	performance := types.Performance{ID: 1, Play: "The CICD by Corneille", StartTime: performanceCICD.StartTime}
+	now := time.Date(2023, time.April, 1, 10, 0, 0, 0, time.UTC)
+	vouchers := dao.NewVoucherDAO()
+	_ = vouchers.SaveVoucher(types.Voucher{Code: "FIRST", Kind: types.VoucherKindPercent, Ratio: big.NewRat(1, 2), MaxUses: 1})
+	service := NewTheaterService(dao.NewReservationDAO(), dao.NewTheaterRoomsDAO(), dao.NewPerformancePriceDAO(), dao.NewVoucherProgramDAO(), dao.NewCustomerSubscriptionDAO(), false,
+		WithClock(clock.Func(func() time.Time { return now })),
+		WithHoldDuration(10*time.Minute),
+		WithVoucherRepository(vouchers),
+	)
+	request := types.ReservationRequest{CustomerID: 2, ReservationCount: 1, Category: types.ZoneCategoryStandard, Performance: performance, VoucherCode: "FIRST"}
+
+	result := service.Reserve(request)
+	if result.Err != nil {
+		t.Fatalf("Failed to reserve: %v", result.Err)
+	}
+	request.CustomerID = 3
+	result = service.Reserve(request)
+	if !errors.Is(result.Err, ErrVoucherUsedUp) {
+		t.Errorf("Expected ErrVoucherUsedUp, got %v", result.Err)
+	}
+
+	// the first reservation expires, giving the use back
+	now = now.Add(time.Hour)
+	_, err := service.ReleaseExpiredHolds()
+	if err != nil {
+		t.Fatalf("Failed to release expired holds: %v", err)
+	}
+	result = service.Reserve(request)
+	if result.Err != nil {
+		t.Errorf("Expected voucher use to be given back on expiry, got %v", result.Err)
+	}
+}
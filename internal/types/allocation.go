@@ -0,0 +1,25 @@
+package types
+
+// SeatAllocation names the strategy choosing the seats of a reservation among the free seats of the room
+type SeatAllocation string
+
+const (
+	// SeatAllocationFirstFit takes the first contiguous seats found, scanning zones and rows in declaration order
+	SeatAllocationFirstFit SeatAllocation = "FIRST_FIT"
+	// SeatAllocationCenter takes the contiguous seats closest to the center of their row, front rows first on ties
+	SeatAllocationCenter SeatAllocation = "CENTER"
+	// SeatAllocationClosestToStage takes the contiguous seats closest to the middle of the stage
+	SeatAllocationClosestToStage SeatAllocation = "CLOSEST_TO_STAGE"
+	// SeatAllocationLeastFragmenting takes the contiguous seats leaving the fewest isolated seats and free runs in their row
+	SeatAllocationLeastFragmenting SeatAllocation = "LEAST_FRAGMENTING"
+)
+
+// SplitSeating tells what to do with the seats offered when no row has enough contiguous free seats
+type SplitSeating string
+
+const (
+	// SplitSeatingAccept books the seats offered, although they are not all side by side
+	SplitSeatingAccept SplitSeating = "ACCEPT"
+	// SplitSeatingReject reports the seats offered, without booking them
+	SplitSeatingReject SplitSeating = "REJECT"
+)
@@ -0,0 +1,59 @@
+package types
+
+import "math/big"
+
+// StackingMode tells how a discount combines with the other discounts of a reservation
+type StackingMode string
+
+const (
+	// StackingMultiply applies the discount to the price left by the discounts of higher priority
+	StackingMultiply StackingMode = "MULTIPLY"
+	// StackingAdditive applies the discount to the initial price, so that additive ratios add up
+	StackingAdditive StackingMode = "ADDITIVE"
+	// StackingBestOnly applies only the largest of the best-only discounts, the other ones are suppressed
+	StackingBestOnly StackingMode = "BEST_ONLY"
+	// StackingExclusive applies the discount alone, all the other discounts are suppressed.
+	// When several discounts are exclusive, the one of highest priority wins.
+	StackingExclusive StackingMode = "EXCLUSIVE"
+)
+
+// Discount is a discount granted by a pricing rule to a reservation
+type Discount struct {
+	// Kind is the kind of the price line of the discount
+	Kind PriceLineKind
+	// Rule names the rule granting the discount, when there are several of its kind, e.g. the subscription tier
+	Rule string
+	// Ratio is the ratio removed from the price the discount applies to
+	Ratio *big.Rat
+	// Priority orders discounts, highest first
+	Priority int
+	Stacking StackingMode
+}
+
+// Name identifies the discount among the ones of a reservation
+func (d Discount) Name() string {
+	if d.Rule == "" {
+		return string(d.Kind)
+	}
+	return string(d.Kind) + " " + d.Rule
+}
+
+// DiscountStatus tells whether a discount has been applied to a reservation
+type DiscountStatus string
+
+const (
+	DiscountStatusApplied DiscountStatus = "APPLIED"
+	// DiscountStatusCapped is the status of a discount only partially applied, since the overall cap was reached
+	DiscountStatusCapped     DiscountStatus = "CAPPED"
+	DiscountStatusSuppressed DiscountStatus = "SUPPRESSED"
+)
+
+// DiscountOutcome tells what became of a discount when the price was computed
+type DiscountOutcome struct {
+	Discount Discount
+	Status   DiscountStatus
+	// Share is the ratio of the initial price actually removed by the discount
+	Share *big.Rat
+	// Reason explains why the discount was suppressed or capped, empty when applied
+	Reason string
+}
@@ -0,0 +1,52 @@
+package types
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+)
+
+// PointValue is what a loyalty point is worth, in units of the billed currency: 100 points make 1€
+var PointValue = big.NewRat(1, 100)
+
+// EarnedPoints returns the loyalty points earned by paying the given amount: one point per whole unit of the currency
+func EarnedPoints(amount money.Money) int64 {
+	if amount.Sign() <= 0 {
+		return 0
+	}
+	r := amount.Rat()
+	return new(big.Int).Quo(r.Num(), r.Denom()).Int64()
+}
+
+// LoyaltyEntryKind tells why the points balance of a customer changed
+type LoyaltyEntryKind string
+
+const (
+	// LoyaltyEntryEarn credits the points earned by a confirmed reservation
+	LoyaltyEntryEarn LoyaltyEntryKind = "EARN"
+	// LoyaltyEntryRedeem debits the points redeemed against the price of a reservation
+	LoyaltyEntryRedeem LoyaltyEntryKind = "REDEEM"
+	// LoyaltyEntryRestore credits back the points redeemed by a reservation which was cancelled or aborted
+	LoyaltyEntryRestore LoyaltyEntryKind = "RESTORE"
+	// LoyaltyEntryRevoke debits the points earned by a reservation which was cancelled
+	LoyaltyEntryRevoke LoyaltyEntryKind = "REVOKE"
+)
+
+// LoyaltyEntry is a line of the points ledger of a customer, debits have negative points
+type LoyaltyEntry struct {
+	CustomerID    int64
+	ReservationID int64
+	Kind          LoyaltyEntryKind
+	Points        int64
+	At            time.Time
+}
+
+// LoyaltyBalance returns the points balance resulting from the entries
+func LoyaltyBalance(entries []LoyaltyEntry) int64 {
+	var balance int64
+	for _, entry := range entries {
+		balance += entry.Points
+	}
+	return balance
+}
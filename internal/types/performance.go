@@ -7,6 +7,9 @@ type PerformanceNature string
 const (
 	PerformanceNaturePreview  PerformanceNature = "PREVIEW"
 	PerformanceNaturePremiere PerformanceNature = "PREMIERE"
+	PerformanceNatureRegular  PerformanceNature = "REGULAR"
+	PerformanceNatureGala     PerformanceNature = "GALA"
+	PerformanceNatureMatinee  PerformanceNature = "MATINEE"
 )
 
 type Performance struct {
@@ -16,4 +19,17 @@ type Performance struct {
 	StartTime         time.Time
 	EndTime           time.Time
 	PerformanceNature PerformanceNature
+	// SaleStartsAt is when the general sale opens, subscribers may book earlier during their priority booking window.
+	// The sale is always open when it is zero.
+	SaleStartsAt time.Time
+	// SeatAllocation is the strategy choosing the seats of the reservations, the default one of the service when empty
+	SeatAllocation SeatAllocation
+}
+
+// SaleOpensAt returns when customers of the given subscription tier can start booking seats for the performance
+func (p Performance) SaleOpensAt(tier SubscriptionTier) time.Time {
+	if p.SaleStartsAt.IsZero() {
+		return p.SaleStartsAt
+	}
+	return p.SaleStartsAt.Add(-tier.Benefits().PriorityBookingWindow)
 }
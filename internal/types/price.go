@@ -0,0 +1,123 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+)
+
+// PerformancePrice is the price list of a performance
+type PerformancePrice struct {
+	// SeatPrice is the base price of a seat
+	SeatPrice money.Money
+	// CategoryRatios are the ratios applied to the base price for each zone category, 1 when missing
+	CategoryRatios map[ZoneCategory]*big.Rat
+	// Demand adjusts seat prices to the occupancy of the room at booking time, nil for fixed prices
+	Demand *DemandPricing
+	// TicketTypeRatios are the ratios applied to the seat price of each ticket type other than adult, in each zone category;
+	// ticket types without a ratio in a category are not sold in this category
+	TicketTypeRatios map[TicketType]map[ZoneCategory]*big.Rat
+	// Groups are the group pricings of the performance, the best one applying to a reservation is granted
+	Groups []GroupPricing
+}
+
+// DemandScope tells which seats are counted to compute the fill ratio of a demand pricing
+type DemandScope string
+
+const (
+	// DemandScopeRoom counts all the seats of the room
+	DemandScopeRoom DemandScope = "ROOM"
+	// DemandScopeZone counts the seats of the zones of the same category as the priced seat
+	DemandScopeZone DemandScope = "ZONE"
+)
+
+// DemandPricing raises or lowers seat prices in tiers, depending on the ratio of seats already taken
+type DemandPricing struct {
+	Scope DemandScope
+	// Tiers are sorted by increasing MinFillRatio
+	Tiers []DemandTier
+}
+
+// DemandTier is a multiplier of the seat price, applying from a given fill ratio
+type DemandTier struct {
+	Name         string
+	MinFillRatio *big.Rat
+	Multiplier   *big.Rat
+}
+
+// Tier returns the tier applying at the given fill ratio: the one with the highest MinFillRatio not above it.
+// It returns false when the fill ratio is below all the tiers.
+func (d DemandPricing) Tier(fillRatio *big.Rat) (DemandTier, bool) {
+	var applied DemandTier
+	found := false
+	for _, tier := range d.Tiers {
+		if tier.MinFillRatio.Cmp(fillRatio) > 0 {
+			break
+		}
+		applied, found = tier, true
+	}
+	return applied, found
+}
+
+// PriceLineKind tells what a line of a bill stands for
+type PriceLineKind string
+
+const (
+	PriceLineBasePrice          PriceLineKind = "BASE_PRICE"
+	PriceLineCategorySurcharge  PriceLineKind = "CATEGORY_SURCHARGE"
+	PriceLineDemandAdjustment   PriceLineKind = "DEMAND_ADJUSTMENT"
+	PriceLineTicketType         PriceLineKind = "TICKET_TYPE"
+	PriceLineGroupDiscount      PriceLineKind = "GROUP_DISCOUNT"
+	PriceLineSubscriberDiscount PriceLineKind = "SUBSCRIBER_DISCOUNT"
+	PriceLineVoucherDiscount    PriceLineKind = "VOUCHER_DISCOUNT"
+	PriceLineVoucherCode        PriceLineKind = "VOUCHER_CODE"
+	PriceLinePointsRedemption   PriceLineKind = "POINTS_REDEMPTION"
+	PriceLineRounding           PriceLineKind = "ROUNDING"
+)
+
+// PriceLine is a line of a bill, discounts have a negative amount
+type PriceLine struct {
+	Kind PriceLineKind
+	// SeatID is the seat the line applies to, empty for lines applying to the whole bill or for quotes
+	SeatID string
+	// Rule names the rule behind the line, when there are several of its kind, e.g. the demand tier
+	Rule   string
+	Amount money.Money
+}
+
+// PriceBreakdown details how the amount due for some seats is computed.
+//
+// Its pricing rules (seat price, category ratios, discounts and rounding mode) are the ones in effect when it was computed,
+// they are kept so that seats can be priced again the same way later on, e.g. for refunds.
+type PriceBreakdown struct {
+	// SeatPrice is the base price of a seat for the performance
+	SeatPrice money.Money
+	// CategoryRatios are the ratios applied to the base price for each zone category, 1 when missing
+	CategoryRatios map[ZoneCategory]*big.Rat
+	// DemandTiers are the demand tiers applied to the seats of each zone category, when demand pricing is enabled
+	DemandTiers map[ZoneCategory]DemandTier
+	// TicketTypeRatios are the ratios applied to the seat price of each ticket type in each zone category, 1 for adults
+	TicketTypeRatios map[TicketType]map[ZoneCategory]*big.Rat
+	// SubscriptionTier is the tier of the fidelity program of the customer, empty when not subscribed
+	SubscriptionTier SubscriptionTier
+	// Discounts are the discounts granted by the pricing rules, they stack according to their priority and stacking mode
+	Discounts []Discount
+	// DiscountCap is the largest ratio of the initial price the discounts may remove altogether, nil when unlimited
+	DiscountCap *big.Rat
+	// Voucher is the voucher code presented by the customer, applied after the discounts and regardless of their cap, nil when none
+	Voucher *Voucher
+	// RedeemedPoints are the loyalty points redeemed against the amount due, last.
+	// When pricing, they are the points the customer asks to redeem, and only the ones needed to pay the whole amount are kept.
+	RedeemedPoints int64
+	// RoundingMode tells how amounts are rounded to the minor unit of the currency
+	RoundingMode money.RoundingMode
+
+	// InitialPrice is the sum of the seat prices, category ratios applied
+	InitialPrice money.Money
+	// DiscountOutcomes tell which discounts were applied and which were suppressed, by decreasing priority
+	DiscountOutcomes []DiscountOutcome
+	// Lines itemize the bill, their amounts add up to TotalAmountDue
+	Lines []PriceLine
+	// TotalAmountDue is the final price
+	TotalAmountDue money.Money
+}
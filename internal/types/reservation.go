@@ -1,6 +1,13 @@
 package types
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+)
 
 type ReservationStatus string
 
@@ -9,13 +16,88 @@ const (
 	ReservationStatusFulfillable ReservationStatus = "FULFILLABLE"
 	ReservationStatusAborted     ReservationStatus = "ABORTED"
 	ReservationStatusCancelled   ReservationStatus = "CANCELLED"
+	ReservationStatusConfirmed   ReservationStatus = "CONFIRMED"
 )
 
+var ErrInvalidTransition = errors.New("invalid reservation status transition")
+
+// reservationTransitions lists the statuses a reservation can move to, from each stored status;
+// the empty status is the one of a reservation being created.
+// FULFILLABLE is only reported to customers, it is never stored.
+var reservationTransitions = map[ReservationStatus][]ReservationStatus{
+	"":                         {ReservationStatusPending, ReservationStatusAborted},
+	ReservationStatusPending:   {ReservationStatusConfirmed, ReservationStatusAborted, ReservationStatusCancelled},
+	ReservationStatusConfirmed: {ReservationStatusCancelled},
+}
+
+// CanTransitionTo tells whether a reservation can move from status s to status next
+func (s ReservationStatus) CanTransitionTo(next ReservationStatus) bool {
+	return slices.Contains(reservationTransitions[s], next)
+}
+
+// ReservationTransition records a change of status of a reservation
+type ReservationTransition struct {
+	From ReservationStatus
+	To   ReservationStatus
+	At   time.Time
+	// CustomerID is the customer who triggered the transition, 0 for the system (e.g. when a hold expires)
+	CustomerID int64
+}
+
 type Reservation struct {
 	ReservationID int64
 	PerformanceID int64
-	Status        ReservationStatus
-	Seats         []string
+	// CustomerID is the customer who made the reservation
+	CustomerID int64
+	Status     ReservationStatus
+	// Seats are the seats currently held or booked by the reservation
+	Seats []string
+	// ReleasedSeats are the seats which were part of the reservation, and have been given back
+	ReleasedSeats []string
+	// SeatCategories gives the zone category of each seat, held or released
+	SeatCategories map[string]ZoneCategory
+	// SeatTicketTypes gives the ticket type of each seat, held or released, seats are adult ones when missing
+	SeatTicketTypes map[string]TicketType
+	// SeatEligibility flags the concession seats, telling whether the visitor must show a proof of eligibility at the door
+	SeatEligibility map[string]Eligibility
+	// SplitSeats tells the seats are not all side by side in a same row, the customer accepted a split seating offer
+	SplitSeats bool
+	// Price is the price of the seats currently held, computed with the rules in effect when the reservation was made
+	Price PriceBreakdown
+	// RefundedAmount is the amount refunded for released seats
+	RefundedAmount money.Money
+	// Voucher records the voucher code used for the reservation, nil when none was presented
+	Voucher *VoucherRedemption
+	// EarnedPoints are the loyalty points credited to the customer for the reservation, once it is confirmed
+	EarnedPoints int64
+	// ExpiresAt is the deadline after which the seats of a pending reservation are released
+	ExpiresAt time.Time
+	// ConfirmedAt is set when the reservation is confirmed and its seats are booked
+	ConfirmedAt time.Time
+	// History lists the status transitions of the reservation, oldest first, it is only appended to
+	History []ReservationTransition
+}
+
+// Transition moves the reservation to the next status and records it in its history,
+// it fails with ErrInvalidTransition when the state machine does not allow it
+func (r *Reservation) Transition(next ReservationStatus, at time.Time, customerID int64) error {
+	if !r.Status.CanTransitionTo(next) {
+		from := r.Status
+		if from == "" {
+			from = "NEW"
+		}
+		return fmt.Errorf("%w: reservation #%d cannot go from %s to %s", ErrInvalidTransition, r.ReservationID, from, next)
+	}
+
+	// clipping makes sure that the history shared with copies of the reservation is never overwritten
+	r.History = append(slices.Clip(r.History), ReservationTransition{
+		From:       r.Status,
+		To:         next,
+		At:         at,
+		CustomerID: customerID,
+	})
+	r.Status = next
+	return nil
 }
 
 func (r *Reservation) String() string {
@@ -0,0 +1,84 @@
+package types
+
+import "github.com/benoitmasson/theater-reservation-kata/internal/money"
+
+// ReservationRequest describes what a customer asks for when booking seats.
+type ReservationRequest struct {
+	CustomerID       int64
+	ReservationCount int
+	Category         ZoneCategory
+	Performance      Performance
+	// SeatIDs are the seats chosen by the customer, such as "C4", reserved instead of ReservationCount seats of Category.
+	// Each seat is priced by the category of its zone, ReservationCount is set to their number.
+	SeatIDs []string
+	// TicketTypes are the ticket types of the seats to reserve, in the order seats are granted; all adults when empty
+	TicketTypes []TicketType
+	// SeatAllocation is the strategy choosing the seats, the one of the performance when empty
+	SeatAllocation SeatAllocation
+	// SplitSeating tells what to do with seats which are not all side by side, offered when no row has enough contiguous free seats.
+	// No seats are offered when empty.
+	SplitSeating SplitSeating
+	// Currency is the currency the customer is billed in, the currency of the performance price when empty
+	Currency money.Currency
+	// VoucherCode is the voucher code presented by the customer, empty when none
+	VoucherCode string
+	// RedeemPoints is the number of loyalty points the customer wants to redeem against the amount due, at most
+	RedeemPoints int64
+	// Locale is the BCP 47 tag of the locale amounts are written in, such as "fr-FR", money.DefaultLocale when empty
+	Locale string
+}
+
+// SeatRejection tells why a seat chosen by the customer cannot be reserved
+type SeatRejection string
+
+const (
+	SeatRejectionUnknown SeatRejection = "UNKNOWN"
+	SeatRejectionBooked  SeatRejection = "BOOKED"
+	SeatRejectionPending SeatRejection = "PENDING"
+	// SeatRejectionVIPReserved is for free seats kept for VIPs by the quota of the performance
+	SeatRejectionVIPReserved SeatRejection = "VIP_RESERVED"
+)
+
+// TicketType returns the ticket type of the i-th seat to reserve
+func (r ReservationRequest) TicketType(i int) TicketType {
+	if i >= len(r.TicketTypes) {
+		return TicketTypeAdult
+	}
+	return r.TicketTypes[i]
+}
+
+// SeatTicketTypes returns the ticket types of all the seats to reserve
+func (r ReservationRequest) SeatTicketTypes() []TicketType {
+	ticketTypes := make([]TicketType, r.ReservationCount)
+	for i := range ticketTypes {
+		ticketTypes[i] = r.TicketType(i)
+	}
+	return ticketTypes
+}
+
+// ReservationResult is the outcome of a ReservationRequest.
+type ReservationResult struct {
+	Request     ReservationRequest
+	Reservation Reservation
+	// Status is the status reported to the customer: FULFILLABLE or ABORTED
+	Status ReservationStatus
+	// Seats are the seats granted to the customer, empty when the reservation is aborted
+	Seats          []string
+	SeatCategories map[string]ZoneCategory
+	// SeatTicketTypes gives the ticket type of each seat, only when the request sets ticket types
+	SeatTicketTypes map[string]TicketType
+	// SeatEligibility flags the concession seats, telling whether the visitor must show a proof of eligibility at the door
+	SeatEligibility map[string]Eligibility
+	Price           PriceBreakdown
+	// SeatRejections tell why the seats chosen by the customer cannot be reserved, when some of them cannot
+	SeatRejections map[string]SeatRejection
+	// SplitOffer are the seats offered to the customer when the request rejects split seating, they are not held
+	SplitOffer []string
+	// Err explains why the reservation was aborted, nil otherwise
+	Err error
+}
+
+// Contiguous tells whether the seats granted are all side by side in a same row
+func (r ReservationResult) Contiguous() bool {
+	return !r.Reservation.SplitSeats
+}
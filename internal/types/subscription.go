@@ -0,0 +1,50 @@
+package types
+
+import (
+	"math/big"
+	"time"
+)
+
+// SubscriptionTier is the level of the fidelity program a customer has subscribed to, empty when none
+type SubscriptionTier string
+
+const (
+	SubscriptionTierNone   SubscriptionTier = ""
+	SubscriptionTierBasic  SubscriptionTier = "BASIC"
+	SubscriptionTierGold   SubscriptionTier = "GOLD"
+	SubscriptionTierPatron SubscriptionTier = "PATRON"
+)
+
+// SubscriptionBenefits are the advantages granted by a subscription tier
+type SubscriptionBenefits struct {
+	// Discount is the ratio removed from the initial price
+	Discount *big.Rat
+	// PriorityBookingWindow is how long before the general sale the customer can book
+	PriorityBookingWindow time.Duration
+	// VIPQuotaAccess allows the customer to book the seats kept for VIPs, unless the VIP quota policy lists its own bypass tiers
+	VIPQuotaAccess bool
+}
+
+var subscriptionBenefits = map[SubscriptionTier]SubscriptionBenefits{
+	SubscriptionTierBasic: {
+		Discount: big.NewRat(10, 100),
+	},
+	SubscriptionTierGold: {
+		Discount:              big.NewRat(175, 1000),
+		PriorityBookingWindow: 48 * time.Hour,
+	},
+	SubscriptionTierPatron: {
+		Discount:              big.NewRat(25, 100),
+		PriorityBookingWindow: 7 * 24 * time.Hour,
+		VIPQuotaAccess:        true,
+	},
+}
+
+// Benefits returns the advantages of the tier, none for customers without subscription
+func (t SubscriptionTier) Benefits() SubscriptionBenefits {
+	benefits, ok := subscriptionBenefits[t]
+	if !ok {
+		return SubscriptionBenefits{Discount: new(big.Rat)}
+	}
+	return benefits
+}
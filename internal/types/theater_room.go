@@ -1,6 +1,9 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"math/big"
+)
 
 type TheaterRoom struct {
 	Zones []Zone
@@ -13,6 +16,9 @@ const (
 	ZoneCategoryPremium  ZoneCategory = "PREMIUM"
 )
 
+// ZoneCategories lists all the zone categories
+var ZoneCategories = []ZoneCategory{ZoneCategoryStandard, ZoneCategoryPremium}
+
 type Zone struct {
 	Rows     []Row
 	Category ZoneCategory
@@ -38,3 +44,48 @@ type Seat struct {
 func (s Seat) String() string {
 	return fmt.Sprintf("Seat{SeatID=%q,Status=%q}", s.SeatID, s.Status)
 }
+
+// FindSeat returns the seat of the given ID and the category of its zone, false when the room has no such seat
+func (r TheaterRoom) FindSeat(seatID string) (Seat, ZoneCategory, bool) {
+	for _, zone := range r.Zones {
+		for _, row := range zone.Rows {
+			for _, seat := range row.Seats {
+				if seat.SeatID == seatID {
+					return seat, zone.Category, true
+				}
+			}
+		}
+	}
+	return Seat{}, "", false
+}
+
+// FillRatio returns the ratio of seats which are not free in the room, 0 for an empty room
+func (r TheaterRoom) FillRatio() *big.Rat {
+	return r.fillRatio(func(Zone) bool { return true })
+}
+
+// CategoryFillRatio returns the ratio of seats which are not free in the zones of the category, 0 when there are none
+func (r TheaterRoom) CategoryFillRatio(category ZoneCategory) *big.Rat {
+	return r.fillRatio(func(zone Zone) bool { return zone.Category == category })
+}
+
+func (r TheaterRoom) fillRatio(includeZone func(Zone) bool) *big.Rat {
+	var total, taken int64
+	for _, zone := range r.Zones {
+		if !includeZone(zone) {
+			continue
+		}
+		for _, row := range zone.Rows {
+			for _, seat := range row.Seats {
+				total++
+				if seat.Status != SeatStatusFree {
+					taken++
+				}
+			}
+		}
+	}
+	if total == 0 {
+		return new(big.Rat)
+	}
+	return big.NewRat(taken, total)
+}
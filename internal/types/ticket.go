@@ -0,0 +1,81 @@
+package types
+
+import "math/big"
+
+// TicketType is the kind of ticket a seat is booked with, it may be priced differently than the full-price adult ticket
+type TicketType string
+
+const (
+	TicketTypeAdult      TicketType = "ADULT"
+	TicketTypeChild      TicketType = "CHILD"
+	TicketTypeSenior     TicketType = "SENIOR"
+	TicketTypeStudent    TicketType = "STUDENT"
+	TicketTypeUnemployed TicketType = "UNEMPLOYED"
+	TicketTypeDisabled   TicketType = "DISABLED"
+)
+
+// TicketTypes lists all the ticket types
+var TicketTypes = []TicketType{TicketTypeAdult, TicketTypeChild, TicketTypeSenior, TicketTypeStudent, TicketTypeUnemployed, TicketTypeDisabled}
+
+// IsConcession tells whether the ticket type is a concession, which visitors must be eligible to
+func (t TicketType) IsConcession() bool {
+	switch t {
+	case TicketTypeSenior, TicketTypeStudent, TicketTypeUnemployed, TicketTypeDisabled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Eligibility tells whether a visitor is known to be eligible to a concession ticket
+type Eligibility string
+
+const (
+	// EligibilityNotRequired is the eligibility of seats which are not concessions, it is never stored
+	EligibilityNotRequired Eligibility = "NOT_REQUIRED"
+	// EligibilityVerified is the eligibility of visitors who have already given a proof
+	EligibilityVerified Eligibility = "VERIFIED"
+	// EligibilityCheckAtDoor is the eligibility of visitors who must show a proof at the door
+	EligibilityCheckAtDoor Eligibility = "CHECK_AT_DOOR"
+	// EligibilityIneligible is the eligibility of visitors known not to be eligible, they cannot book the concession
+	EligibilityIneligible Eligibility = "INELIGIBLE"
+)
+
+// DoorCheckEntry is a concession seat of a performance, for the staff checking the proofs of eligibility at the door
+type DoorCheckEntry struct {
+	ReservationID int64
+	CustomerID    int64
+	SeatID        string
+	Category      ZoneCategory
+	TicketType    TicketType
+	Eligibility   Eligibility
+}
+
+// GroupPricing is a discount granted to reservations of enough seats, or with a given composition of ticket types
+type GroupPricing struct {
+	// Name identifies the group pricing, e.g. "GROUP" or "FAMILY"
+	Name string
+	// MinSeats is the number of seats to reserve at least, 0 when any number will do
+	MinSeats int
+	// Tickets is the number of tickets of each type to reserve at least, e.g. 2 adults and 2 children for a family
+	Tickets map[TicketType]int
+	// Discount is the ratio removed from the price of the reservation
+	Discount *big.Rat
+}
+
+// Matches tells whether the group pricing applies to a reservation of seats with the given ticket types
+func (g GroupPricing) Matches(ticketTypes []TicketType) bool {
+	if len(ticketTypes) < g.MinSeats {
+		return false
+	}
+	counts := make(map[TicketType]int, len(g.Tickets))
+	for _, ticketType := range ticketTypes {
+		counts[ticketType]++
+	}
+	for ticketType, count := range g.Tickets {
+		if counts[ticketType] < count {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"math/big"
+	"slices"
+	"time"
+)
+
+// VIPQuotaPolicy keeps seats for the VIPs of the performances of a nature,
+// reservations are refused when they would leave fewer free seats than the ones kept
+type VIPQuotaPolicy struct {
+	// RoomThreshold is the ratio of all the seats of the room which must be left free, whatever their category; none when nil
+	RoomThreshold *big.Rat
+	// CategoryThresholds are the ratios of the seats of each zone category which must be left free in the category,
+	// they only apply to reservations of seats of the category
+	CategoryThresholds map[ZoneCategory]*big.Rat
+	// BypassTiers are the subscription tiers whose subscribers may book the seats kept for VIPs,
+	// the ones whose benefits grant VIP quota access when nil
+	BypassTiers []SubscriptionTier
+	// ReleaseBefore is how long before the start of the performance the seats kept for VIPs go on general sale,
+	// they are kept until the end when it is zero
+	ReleaseBefore time.Duration
+}
+
+// Bypassed tells whether subscribers of the tier may book the seats kept for VIPs
+func (p VIPQuotaPolicy) Bypassed(tier SubscriptionTier) bool {
+	if tier == SubscriptionTierNone {
+		return false
+	}
+	if p.BypassTiers == nil {
+		return tier.Benefits().VIPQuotaAccess
+	}
+	return slices.Contains(p.BypassTiers, tier)
+}
+
+// ReleaseDate returns when the seats kept for the VIPs of the performance go on general sale, false when they never do
+func (p VIPQuotaPolicy) ReleaseDate(performance Performance) (time.Time, bool) {
+	if p.ReleaseBefore == 0 {
+		return time.Time{}, false
+	}
+	return performance.StartTime.Add(-p.ReleaseBefore), true
+}
+
+// KeptSeats returns the number of seats kept out of totalSeats for the threshold ratio, rounded down
+func KeptSeats(threshold *big.Rat, totalSeats int) int {
+	kept := new(big.Int).Mul(big.NewInt(int64(totalSeats)), threshold.Num())
+	return int(kept.Quo(kept, threshold.Denom()).Int64())
+}
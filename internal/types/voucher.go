@@ -0,0 +1,79 @@
+package types
+
+import (
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
+)
+
+// VoucherKind tells how a voucher code discounts the price
+type VoucherKind string
+
+const (
+	// VoucherKindPercent removes a ratio of the price
+	VoucherKindPercent VoucherKind = "PERCENT"
+	// VoucherKindFixed removes a fixed amount from the price, down to zero
+	VoucherKindFixed VoucherKind = "FIXED"
+)
+
+// Voucher is a discount code presented by a customer when booking
+type Voucher struct {
+	Code string
+	Kind VoucherKind
+	// Ratio is the ratio removed from the price, for percent vouchers
+	Ratio *big.Rat
+	// Amount is the amount removed from the price, for fixed vouchers
+	Amount money.Money
+
+	// ValidFrom and ValidUntil bound the booking times the voucher can be used at, ValidUntil excluded.
+	// The window is open on the sides where they are zero.
+	ValidFrom  time.Time
+	ValidUntil time.Time
+
+	// MaxUses is the number of bookings the voucher can be used for, unlimited when 0
+	MaxUses int
+	// MaxUsesPerCustomer is the number of bookings each customer can use the voucher for, unlimited when 0
+	MaxUsesPerCustomer int
+
+	// PerformanceIDs restricts the voucher to some performances, it applies to all of them when empty
+	PerformanceIDs []int64
+	// Categories restricts the voucher to some zone categories, it applies to all of them when empty
+	Categories []ZoneCategory
+}
+
+// IsValidAt tells whether the voucher can be used for a booking made at the given time
+func (v Voucher) IsValidAt(bookedAt time.Time) bool {
+	if !v.ValidFrom.IsZero() && bookedAt.Before(v.ValidFrom) {
+		return false
+	}
+	if !v.ValidUntil.IsZero() && !bookedAt.Before(v.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo tells whether the voucher can be used for seats of the category at the performance
+func (v Voucher) AppliesTo(performanceID int64, category ZoneCategory) bool {
+	if len(v.PerformanceIDs) > 0 && !slices.Contains(v.PerformanceIDs, performanceID) {
+		return false
+	}
+	if len(v.Categories) > 0 && !slices.Contains(v.Categories, category) {
+		return false
+	}
+	return true
+}
+
+// VoucherRedemption records the use of a voucher code by a reservation
+type VoucherRedemption struct {
+	Code       string
+	RedeemedAt time.Time
+	// ReleasedAt is when the use was given back, because the reservation was cancelled or aborted; zero while it counts
+	ReleasedAt time.Time
+}
+
+// IsActive tells whether the redemption counts towards the usage caps of the voucher
+func (r VoucherRedemption) IsActive() bool {
+	return r.ReleasedAt.IsZero()
+}
@@ -1,21 +1,71 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log"
+	"os"
 	"time"
 
 	"github.com/benoitmasson/theater-reservation-kata/internal/dao"
+	"github.com/benoitmasson/theater-reservation-kata/internal/encoder"
+	"github.com/benoitmasson/theater-reservation-kata/internal/money"
 	"github.com/benoitmasson/theater-reservation-kata/internal/service"
 	"github.com/benoitmasson/theater-reservation-kata/internal/types"
 )
 
 func main() {
+	dbPath := flag.String("db", "", "path to a SQLite database file to persist rooms and reservations, in-memory storage when empty")
+	ratesPath := flag.String("rates", "", "path to a JSON file of fixed currency conversion rates, to bill customers in another currency than the one of the performance")
+	pricesPath := flag.String("prices", "", "path to a JSON price list file, reloaded when modified, hard-coded prices when empty")
+	doorCheck := flag.Int64("door-check", 0, "ID of a performance whose concession seats are written as CSV for the door check, after the sample reservations")
+	flag.Parse()
+
+	var reservationDAO dao.ReservationRepository = dao.NewReservationDAO()
+	var theaterRoomsDAO dao.TheaterRoomRepository = dao.NewTheaterRoomsDAO()
+	var options []service.Option
+	if *dbPath != "" {
+		store, err := openStore(*dbPath, theaterRoomsDAO)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer store.Close()
+
+		reservationDAO = store
+		theaterRoomsDAO = store
+		options = append(options, service.WithTransactor(store), service.WithLoyaltyLedger(store))
+	}
+	if *ratesPath != "" {
+		table, err := money.LoadConversionTable(*ratesPath)
+		if err != nil {
+			log.Fatalf("Failed to load conversion rates: %v", err)
+		}
+		options = append(options, service.WithConversionTable(table))
+	}
+
+	var performancePriceDAO dao.PerformancePriceRepository = dao.NewPerformancePriceDAO()
+	if *pricesPath != "" {
+		priceList, err := dao.LoadPriceList(*pricesPath)
+		if err != nil {
+			log.Fatalf("Failed to load price list: %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go priceList.Watch(ctx, time.Second, func(err error) {
+			log.Printf("Failed to reload price list, keeping previous prices: %v", err)
+		})
+		performancePriceDAO = priceList
+	}
+
 	theaterService := service.NewTheaterService(
-		dao.NewReservationDAO(),
-		dao.NewTheaterRoomsDAO(),
-		dao.NewPerformancePriceDAO(),
+		reservationDAO,
+		theaterRoomsDAO,
+		performancePriceDAO,
 		dao.NewVoucherProgramDAO(),
+		dao.NewCustomerSubscriptionDAO(),
 		false, /* debug */
+		options...,
 	)
 
 	performance := types.Performance{
@@ -34,4 +84,46 @@ func main() {
 		PerformanceNature: types.PerformanceNaturePreview,
 	}
 	fmt.Println(theaterService.Reservation(2, 4, types.ZoneCategoryStandard, performance2))
+
+	if *doorCheck != 0 {
+		entries, err := theaterService.DoorCheckList(*doorCheck)
+		if err != nil {
+			log.Fatalf("Failed to list door checks: %v", err)
+		}
+		err = encoder.WriteDoorCheckCSV(os.Stdout, entries)
+		if err != nil {
+			log.Fatalf("Failed to write door checks: %v", err)
+		}
+	}
+}
+
+// openStore opens the SQLite database at path, and fills it with the sample rooms on first use
+func openStore(path string, sampleRooms dao.TheaterRoomRepository) (*dao.SQLiteStore, error) {
+	store, err := dao.OpenSQLiteStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for performanceID := int64(1); performanceID <= 3; performanceID++ {
+		room, err := store.FetchTheaterRoom(performanceID)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		if len(room.Zones) > 0 {
+			continue
+		}
+		room, err = sampleRooms.FetchTheaterRoom(performanceID)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		err = store.SaveTheaterRoom(performanceID, room)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+
+	return store, nil
 }